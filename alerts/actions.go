@@ -0,0 +1,155 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Action says what to do when a rule fires.
+//
+//	log     writes a one line summary with log.Printf
+//	stdout  writes the alert as a JSON line to standard output
+//	webhook POSTs the alert as JSON to URL
+//	notify  POSTs the summary as plain text to URL, with the rule name in a
+//	        Title header, which push services such as ntfy accept directly
+type Action struct {
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+}
+
+func (a Action) validate() error {
+	switch a.Type {
+	case "log", "stdout":
+		return nil
+	case "webhook", "notify":
+		if a.URL == "" {
+			return fmt.Errorf("%s action needs a url", a.Type)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", a.Type)
+}
+
+var (
+	stdoutMu   sync.Mutex
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// fire runs the action. Network actions are handed to p so a slow endpoint
+// never holds up the dispatch loop.
+func (a Action) fire(alert *Alert, p *poster) {
+	switch a.Type {
+	case "log":
+		log.Printf("Alert %s\n", alert.Summary)
+	case "stdout":
+		stdoutMu.Lock()
+		defer stdoutMu.Unlock()
+		if err := json.NewEncoder(os.Stdout).Encode(alert); err != nil {
+			log.Printf("Error writing alert: %v\n", err)
+		}
+	case "webhook":
+		body, err := json.Marshal(alert)
+		if err != nil {
+			log.Printf("Error encoding alert: %v\n", err)
+			return
+		}
+		p.post(request{url: a.URL, contentType: "application/json", body: body})
+	case "notify":
+		header := http.Header{"Title": {alert.Rule}}
+		p.post(request{url: a.URL, contentType: "text/plain; charset=utf-8", header: header, body: []byte(alert.Summary)})
+	}
+}
+
+const (
+	postWorkers = 4
+	postQueue   = 256
+)
+
+type request struct {
+	url         string
+	contentType string
+	header      http.Header
+	body        []byte
+}
+
+// poster sends alerts to network endpoints from a few workers. Alerts that
+// arrive while its queue is full are dropped and logged.
+type poster struct {
+	queue chan request
+	wg    sync.WaitGroup
+}
+
+func newPoster() *poster {
+	p := &poster{queue: make(chan request, postQueue)}
+	for i := 0; i < postWorkers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for r := range p.queue {
+				send(r)
+			}
+		}()
+	}
+	return p
+}
+
+func (p *poster) post(r request) {
+	select {
+	case p.queue <- r:
+	default:
+		log.Printf("Error sending alert to %s: queue full, alert dropped\n", r.url)
+	}
+}
+
+// close sends whatever is queued and waits for it.
+func (p *poster) close() {
+	close(p.queue)
+	p.wg.Wait()
+}
+
+func send(r request) {
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(r.body))
+	if err != nil {
+		log.Printf("Error sending alert to %s: %v\n", r.url, err)
+		return
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", r.contentType)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Printf("Error sending alert to %s: %v\n", r.url, err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		log.Printf("Alert endpoint %s answered %s\n", r.url, resp.Status)
+	}
+}
+
+// summarise builds the human readable line used by log and notify.
+func summarise(alert *Alert) string {
+	parts := []string{alert.Rule + ":"}
+	for _, src := range []map[string]interface{}{alert.Match, alert.Message} {
+		for _, k := range []string{"StarSystem", "SystemName", "System", "SignalName", "USSType", "SpawningFaction", "ThreatLevel", "Name"} {
+			if v, ok := src[k]; ok {
+				parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+			}
+		}
+	}
+	if alert.Distance != nil {
+		parts = append(parts, fmt.Sprintf("distance=%.1fly", *alert.Distance))
+	}
+	return strings.Join(parts, " ")
+}
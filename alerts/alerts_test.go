@@ -0,0 +1,247 @@
+package alerts
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"EDDN/eddn"
+)
+
+func envelope(t *testing.T, ref, msg string) *eddn.Envelope {
+	t.Helper()
+	schema, ok := eddn.ParseSchemaRef(ref)
+	if !ok {
+		t.Fatalf("bad schema ref %s", ref)
+	}
+	return &eddn.Envelope{SchemaRef: ref, Schema: schema, Raw: json.RawMessage(msg)}
+}
+
+const signalsRef = "https://eddn.edcd.io/schemas/fsssignaldiscovered/1"
+
+// collector is an endpoint that records the alerts webhook actions post.
+type collector struct {
+	*httptest.Server
+	mu     sync.Mutex
+	alerts []Alert
+	titles []string
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/plain") {
+			c.titles = append(c.titles, r.Header.Get("Title")+": "+string(body))
+			return
+		}
+		var a Alert
+		if err := json.Unmarshal(body, &a); err != nil {
+			t.Errorf("alert body %s: %v", body, err)
+		}
+		c.alerts = append(c.alerts, a)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// engine compiles rules given as JSON, sending their alerts to c.
+func engine(t *testing.T, c *collector, rules string) *Engine {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	rules = strings.ReplaceAll(rules, "URL", c.URL)
+	if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestPredicates(t *testing.T) {
+	msg := map[string]interface{}{
+		"StarSystem":  "Shinrarta Dezhra",
+		"ThreatLevel": float64(2),
+		"Faction":     nil,
+	}
+	tests := []struct {
+		field, op string
+		value     interface{}
+		want      bool
+	}{
+		{"StarSystem", "eq", "shinrarta dezhra", true},
+		{"StarSystem", "eq", "Sol", false},
+		{"StarSystem", "ne", "Sol", true},
+		{"Missing", "ne", "Sol", true},
+		{"Missing", "eq", "Sol", false},
+		{"StarSystem", "contains", "DEZHRA", true},
+		{"StarSystem", "prefix", "Shinrarta", true},
+		{"StarSystem", "prefix", "shinrarta", false},
+		{"StarSystem", "regex", "^Shin.*ra$", true},
+		{"StarSystem", "regex", "^Sol$", false},
+		{"ThreatLevel", "eq", float64(2), true},
+		{"ThreatLevel", "lt", float64(2), false},
+		{"ThreatLevel", "le", float64(2), true},
+		{"ThreatLevel", "gt", float64(1), true},
+		{"ThreatLevel", "ge", float64(3), false},
+		{"StarSystem", "gt", float64(1), false},
+		{"ThreatLevel", "contains", "2", false},
+		{"StarSystem", "exists", nil, true},
+		{"Faction", "exists", true, true},
+		{"Missing", "exists", nil, false},
+		{"Missing", "exists", false, true},
+	}
+	for _, tt := range tests {
+		r := Rule{Schema: "x", Actions: []Action{{Type: "log"}}, Match: []Predicate{{Field: tt.field, Op: tt.op, Value: tt.value}}}
+		if err := r.compile(); err != nil {
+			t.Fatalf("%s %s %v: %v", tt.field, tt.op, tt.value, err)
+		}
+		if got := r.matches(msg, nil); got != tt.want {
+			t.Errorf("%s %s %v = %v, want %v", tt.field, tt.op, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		rule, err string
+	}{
+		{`{"name": "a", "actions": [{"type": "log"}]}`, "no schema"},
+		{`{"name": "a", "schema": "x"}`, "no actions"},
+		{`{"name": "a", "schema": "x", "match": [{"field": "f", "op": "like"}], "actions": [{"type": "log"}]}`, `unknown op "like"`},
+		{`{"name": "a", "schema": "x", "match": [{"field": "f", "op": "regex", "value": "("}], "actions": [{"type": "log"}]}`, "missing closing )"},
+		{`{"name": "a", "schema": "x", "match": [{"field": "f", "op": "regex", "value": 1}], "actions": [{"type": "log"}]}`, "must be a string"},
+		{`{"name": "a", "schema": "x", "actions": [{"type": "email"}]}`, `unknown action "email"`},
+		{`{"name": "a", "schema": "x", "actions": [{"type": "webhook"}]}`, "webhook action needs a url"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		os.WriteFile(path, []byte(`{"rules": [`+tt.rule+`]}`), 0o644)
+		_, err := LoadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want one containing %q", tt.rule, err, tt.err)
+		}
+	}
+}
+
+func TestEachAndNear(t *testing.T) {
+	c := newCollector(t)
+	e := engine(t, c, `{"rules": [{
+		"name": "boom salvage", "schema": "fsssignaldiscovered", "each": "signals",
+		"match": [
+			{"field": "USSType", "op": "eq", "value": "$USS_Type_ValuableSalvage;"},
+			{"field": "StarSystem", "op": "prefix", "value": "Col"}
+		],
+		"near": {"starPos": [0, 0, 0], "maxDistance": 100},
+		"actions": [{"type": "webhook", "url": "URL"}]
+	}]}`)
+
+	e.Evaluate(envelope(t, signalsRef, `{"StarSystem": "Col 285 Sector AB-C d1", "StarPos": [30, 40, 0], "signals": [
+		{"SignalName": "$USS_SalvageHaulageWreckage;", "USSType": "$USS_Type_Salvage;"},
+		{"SignalName": "$USS_SalvageHaulageWreckage;", "USSType": "$USS_Type_ValuableSalvage;", "ThreatLevel": 1},
+		{"SignalName": "$USS_SalvageHaulageWreckage;", "USSType": "$USS_Type_ValuableSalvage;", "ThreatLevel": 3}
+	]}`))
+	// Out of range, and with no position at all.
+	e.Evaluate(envelope(t, signalsRef, `{"StarSystem": "Col 285 Sector AB-C d2", "StarPos": [100, 100, 0], "signals": [
+		{"USSType": "$USS_Type_ValuableSalvage;"}]}`))
+	e.Evaluate(envelope(t, signalsRef, `{"StarSystem": "Col 285 Sector AB-C d3", "signals": [
+		{"USSType": "$USS_Type_ValuableSalvage;"}]}`))
+	// Another schema.
+	e.Evaluate(envelope(t, "https://eddn.edcd.io/schemas/fssdiscoveryscan/1", `{"StarSystem": "Col 285", "StarPos": [0, 0, 0],
+		"USSType": "$USS_Type_ValuableSalvage;"}`))
+	e.Close()
+
+	if len(c.alerts) != 2 {
+		t.Fatalf("%d alerts, want one per matching signal: %+v", len(c.alerts), c.alerts)
+	}
+	for i, a := range c.alerts {
+		if a.Rule != "boom salvage" || a.Distance == nil || *a.Distance != 50 {
+			t.Errorf("alert %d: %+v", i, a)
+		}
+		if a.Match["ThreatLevel"] != float64(2*i+1) {
+			t.Errorf("alert %d matched %v", i, a.Match)
+		}
+		if !strings.Contains(a.Summary, "StarSystem=Col 285 Sector AB-C d1") || !strings.Contains(a.Summary, "distance=50.0ly") {
+			t.Errorf("alert %d summary %q", i, a.Summary)
+		}
+	}
+}
+
+func TestCloseDrainsPoster(t *testing.T) {
+	c := newCollector(t)
+	e := engine(t, c, `{"rules": [{
+		"name": "codex", "schema": "codexentry",
+		"actions": [{"type": "webhook", "url": "URL"}, {"type": "notify", "url": "URL"}]
+	}]}`)
+	const n = 100
+	for i := 0; i < n; i++ {
+		e.Evaluate(envelope(t, "https://eddn.edcd.io/schemas/codexentry/1", `{"System": "Sol", "Name": "$Codex_Ent_Guardian;"}`))
+	}
+	e.Close()
+
+	// Everything queued before Close has been sent once it returns.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.alerts) != n || len(c.titles) != n {
+		t.Fatalf("%d webhook and %d notify posts, want %d each", len(c.alerts), len(c.titles), n)
+	}
+	if c.titles[0] != "codex: codex: System=Sol Name=$Codex_Ent_Guardian;" {
+		t.Errorf("notify post %q", c.titles[0])
+	}
+}
+
+// TestExampleHGE checks that the High Grade Emissions rule shipped in
+// alerts.example.json fires like the check it replaced did: once for
+// every High Grade Emissions signal, and for no other signal.
+func TestExampleHGE(t *testing.T) {
+	cfg, err := LoadConfig("../alerts.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hge *Rule
+	for i := range cfg.Rules {
+		if strings.Contains(cfg.Rules[i].Name, "High Grade Emissions") {
+			hge = &cfg.Rules[i]
+		}
+	}
+	if hge == nil {
+		t.Fatal("no High Grade Emissions rule in alerts.example.json")
+	}
+
+	c := newCollector(t)
+	hge.Actions = []Action{{Type: "webhook", URL: c.URL}}
+	e := NewEngine(&Config{Rules: []Rule{*hge}})
+
+	e.Evaluate(envelope(t, signalsRef, `{"event": "FSSSignalDiscovered", "StarSystem": "Wolf 359", "StarPos": [3.875, 6.46875, -1.90625],
+		"signals": [
+			{"SignalName": "$USS_HighGradeEmissions;", "SignalType": "USS", "USSType": "$USS_Type_VeryValuableSalvage;",
+				"SpawningFaction": "$faction_none;", "ThreatLevel": 0},
+			{"SignalName": "$USS_SalvageHaulageWreckage;", "SignalType": "USS", "USSType": "$USS_Type_Salvage;", "ThreatLevel": 0},
+			{"SignalName": "$USS_EncodedEmissions;", "SignalType": "USS", "USSType": "$USS_Type_ValuableSalvage;", "ThreatLevel": 2},
+			{"SignalName": "Wolf 359 Mining Hub", "SignalType": "StationCoriolis", "IsStation": true},
+			{"SignalName": "$USS_HighGradeEmissions;", "SignalType": "USS", "USSType": "$USS_Type_VeryValuableSalvage;",
+				"SpawningFaction": "Wolf 359 Purple Posse", "ThreatLevel": 0}
+		]}`))
+	e.Evaluate(envelope(t, signalsRef, `{"event": "FSSSignalDiscovered", "StarSystem": "Sol", "StarPos": [0, 0, 0],
+		"signals": [{"SignalName": "Abraham Lincoln", "SignalType": "StationOrbis", "IsStation": true}]}`))
+	e.Close()
+
+	if len(c.alerts) != 2 {
+		t.Fatalf("%d alerts, want one per High Grade Emissions signal: %+v", len(c.alerts), c.alerts)
+	}
+	for _, a := range c.alerts {
+		if a.Match["SignalName"] != "$USS_HighGradeEmissions;" || a.Message["StarSystem"] != "Wolf 359" {
+			t.Errorf("alert for %v in %v", a.Match["SignalName"], a.Message["StarSystem"])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package alerts
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// Alert is what actions receive when a rule matches.
+type Alert struct {
+	Rule      string                 `json:"rule"`
+	SchemaRef string                 `json:"schemaRef"`
+	Header    eddn.EDDNHeader        `json:"header"`
+	Message   map[string]interface{} `json:"message"`
+	Match     map[string]interface{} `json:"match,omitempty"`
+	Distance  *float64               `json:"distance,omitempty"`
+	Fired     time.Time              `json:"fired"`
+	Summary   string                 `json:"summary"`
+}
+
+// Engine evaluates a set of rules against envelopes.
+type Engine struct {
+	rules  []Rule
+	poster *poster
+}
+
+func NewEngine(cfg *Config) *Engine {
+	return &Engine{rules: cfg.Rules, poster: newPoster()}
+}
+
+// Close waits for queued webhook and notify alerts to be sent. The engine
+// must not be used afterwards.
+func (e *Engine) Close() error {
+	e.poster.close()
+	return nil
+}
+
+// Load reads a rule file and returns an engine for it.
+func Load(path string) (*Engine, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(cfg), nil
+}
+
+// Attach evaluates every message dispatched by d.
+func (e *Engine) Attach(d *eddn.Dispatcher) {
+	d.HandleAny(e.Evaluate)
+}
+
+// Evaluate runs the rules for env's schema and fires the actions of every
+// rule that matches.
+func (e *Engine) Evaluate(env *eddn.Envelope) {
+	var msg map[string]interface{}
+	for i := range e.rules {
+		r := &e.rules[i]
+		if !r.appliesTo(env.Schema) {
+			continue
+		}
+		// The generic form is only decoded once a rule needs it.
+		if msg == nil {
+			if err := json.Unmarshal(env.Raw, &msg); err != nil {
+				log.Printf("Error decoding message for alerts: %v\n", err)
+				return
+			}
+		}
+
+		dist, ok := r.near(msg)
+		if !ok {
+			continue
+		}
+		for _, elem := range r.elements(msg) {
+			if !r.matches(msg, elem) {
+				continue
+			}
+			alert := &Alert{
+				Rule:      r.Name,
+				SchemaRef: env.SchemaRef,
+				Header:    env.Header,
+				Message:   msg,
+				Match:     elem,
+				Fired:     time.Now().UTC(),
+			}
+			if r.Near != nil {
+				alert.Distance = &dist
+			}
+			alert.Summary = summarise(alert)
+			for _, a := range r.Actions {
+				a.fire(alert, e.poster)
+			}
+		}
+	}
+}
+
+// elements returns the maps the predicates run against: each object in the
+// Each array, or a single nil element so only the message itself is checked.
+func (r *Rule) elements(msg map[string]interface{}) []map[string]interface{} {
+	if r.Each == "" {
+		return []map[string]interface{}{nil}
+	}
+	list, _ := msg[r.Each].([]interface{})
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,210 @@
+// Package alerts evaluates user supplied rules against decoded EDDN
+// messages and fires actions when they match.
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"EDDN/eddn"
+	"EDDN/galaxy"
+)
+
+// Config is the on-disk rule file.
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule matches messages of one schema. When Each names an array field of the
+// message, the predicates are tried against every element and the rule fires
+// once per matching element; fields missing from the element fall back to
+// the message itself.
+type Rule struct {
+	Name    string      `json:"name"`
+	Schema  string      `json:"schema"`
+	Each    string      `json:"each,omitempty"`
+	Match   []Predicate `json:"match,omitempty"`
+	Near    *Near       `json:"near,omitempty"`
+	Actions []Action    `json:"actions"`
+}
+
+// Predicate compares one field with Value. Op is one of eq, ne, contains,
+// prefix, regex, lt, le, gt, ge or exists.
+type Predicate struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Near limits a rule to messages whose StarPos lies within MaxDistance light
+// years of StarPos.
+type Near struct {
+	StarPos     [3]float64 `json:"starPos"`
+	MaxDistance float64    `json:"maxDistance"`
+}
+
+// LoadConfig reads and validates a rule file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range cfg.Rules {
+		if err := cfg.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s: rule %q: %v", path, cfg.Rules[i].Name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Schema == "" {
+		return fmt.Errorf("no schema")
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("no actions")
+	}
+	for i := range r.Match {
+		p := &r.Match[i]
+		switch p.Op {
+		case "eq", "ne", "contains", "prefix", "lt", "le", "gt", "ge", "exists":
+		case "regex":
+			s, ok := p.Value.(string)
+			if !ok {
+				return fmt.Errorf("regex for %s must be a string", p.Field)
+			}
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return err
+			}
+			p.re = re
+		default:
+			return fmt.Errorf("unknown op %q for %s", p.Op, p.Field)
+		}
+	}
+	for _, a := range r.Actions {
+		if err := a.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliesTo reports whether the rule is for schema. Rules may give the
+// full $schemaRef or just its family, e.g. "fsssignaldiscovered".
+func (r *Rule) appliesTo(schema eddn.Schema) bool {
+	if r.Schema == schema.Ref() {
+		return true
+	}
+	return strings.EqualFold(r.Schema, schema.Family)
+}
+
+// near reports whether msg is within range and, if the rule has a Near
+// clause, how far away it is.
+func (r *Rule) near(msg map[string]interface{}) (float64, bool) {
+	if r.Near == nil {
+		return 0, true
+	}
+	pos, ok := starPos(msg["StarPos"])
+	if !ok {
+		return 0, false
+	}
+	d := galaxy.Distance(r.Near.StarPos, pos)
+	return d, d <= r.Near.MaxDistance
+}
+
+func starPos(v interface{}) ([3]float64, bool) {
+	var pos [3]float64
+	list, ok := v.([]interface{})
+	if !ok || len(list) != 3 {
+		return pos, false
+	}
+	for i, c := range list {
+		f, ok := c.(float64)
+		if !ok {
+			return pos, false
+		}
+		pos[i] = f
+	}
+	return pos, true
+}
+
+// matches evaluates every predicate against elem, falling back to msg.
+func (r *Rule) matches(msg, elem map[string]interface{}) bool {
+	for i := range r.Match {
+		p := &r.Match[i]
+		v, ok := elem[p.Field]
+		if !ok {
+			v, ok = msg[p.Field]
+		}
+		if !p.test(v, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Predicate) test(v interface{}, present bool) bool {
+	if p.Op == "exists" {
+		want, _ := p.Value.(bool)
+		if p.Value == nil {
+			want = true
+		}
+		return present == want
+	}
+	if !present {
+		return p.Op == "ne"
+	}
+
+	switch p.Op {
+	case "eq":
+		return equal(v, p.Value)
+	case "ne":
+		return !equal(v, p.Value)
+	case "contains":
+		s, ok := v.(string)
+		want, _ := p.Value.(string)
+		return ok && strings.Contains(strings.ToLower(s), strings.ToLower(want))
+	case "prefix":
+		s, ok := v.(string)
+		want, _ := p.Value.(string)
+		return ok && strings.HasPrefix(s, want)
+	case "regex":
+		s, ok := v.(string)
+		return ok && p.re.MatchString(s)
+	}
+
+	a, ok1 := v.(float64)
+	b, ok2 := p.Value.(float64)
+	if !ok1 || !ok2 {
+		return false
+	}
+	switch p.Op {
+	case "lt":
+		return a < b
+	case "le":
+		return a <= b
+	case "gt":
+		return a > b
+	case "ge":
+		return a >= b
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if s, ok := a.(string); ok {
+		t, ok := b.(string)
+		return ok && strings.EqualFold(s, t)
+	}
+	return a == b
+}
@@ -0,0 +1,412 @@
+// Package api serves the collected market data over HTTP as JSON.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"EDDN/galaxy"
+	"EDDN/outfitting"
+	"EDDN/stations"
+	"EDDN/store"
+)
+
+const defaultLimit = 20
+
+// maxNearbySystems bounds how many systems a proximity query considers.
+const maxNearbySystems = 2000
+
+// Server answers market queries from a Store. Proximity queries use the
+// system catalogue.
+type Server struct {
+	store   *store.Store
+	systems *galaxy.Catalogue
+	mux     *http.ServeMux
+}
+
+func NewServer(st *store.Store, systems *galaxy.Catalogue) *Server {
+	s := &Server{store: st, systems: systems, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/commodities/", s.commodities)
+	s.mux.HandleFunc("/markets/", s.market)
+	s.mux.HandleFunc("/systems/", s.systemMarkets)
+	s.mux.HandleFunc("/modules/", s.stockists(store.Modules, outfitting.ModuleName))
+	s.mux.HandleFunc("/ships/", s.stockists(store.Ships, outfitting.ShipName))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// GET /commodities/{name}/best-buy, /commodities/{name}/best-sell and
+// /commodities/{name}/nearest?from={system}
+func (s *Server) commodities(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/commodities/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	f, limit, err := parseFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if parts[1] == "nearest" {
+		s.nearestSellers(w, r, parts[0], f, limit)
+		return
+	}
+
+	var listings []store.Listing
+	switch parts[1] {
+	case "best-buy":
+		listings, err = s.store.BestBuy(parts[0], f, limit)
+	case "best-sell":
+		listings, err = s.store.BestSell(parts[0], f, limit)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	human := wantHuman(r)
+	views := make([]listingView, 0, len(listings))
+	for _, l := range listings {
+		views = append(views, listingView{Market: l.Market, Price: newPriceView(l.Price, human)})
+	}
+	writeJSON(w, views)
+}
+
+// nearestSellers lists the markets selling a commodity closest to the
+// system named by ?from=, searching ?radius= light years (default 100).
+func (s *Server) nearestSellers(w http.ResponseWriter, r *http.Request, name string, f store.Filter, limit int) {
+	from, ok := s.systems.ByName(r.URL.Query().Get("from"))
+	if !ok {
+		httpError(w, http.StatusBadRequest, "unknown or missing from system")
+		return
+	}
+	radius := 100.0
+	if v := r.URL.Query().Get("radius"); v != "" {
+		var err error
+		if radius, err = strconv.ParseFloat(v, 64); err != nil || radius <= 0 {
+			httpError(w, http.StatusBadRequest, "invalid radius")
+			return
+		}
+	}
+
+	distances := make(map[string]float64)
+	var names []string
+	for _, sys := range s.systems.Nearest(from.Pos, maxNearbySystems) {
+		d := galaxy.Distance(from.Pos, sys.Pos)
+		if d > radius {
+			break
+		}
+		distances[strings.ToLower(sys.Name)] = d
+		names = append(names, sys.Name)
+	}
+
+	listings, err := s.store.SellersIn(name, names, f)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	sort.SliceStable(listings, func(i, j int) bool {
+		return distances[strings.ToLower(listings[i].Market.SystemName)] < distances[strings.ToLower(listings[j].Market.SystemName)]
+	})
+	if len(listings) > limit {
+		listings = listings[:limit]
+	}
+
+	human := wantHuman(r)
+	views := make([]listingView, 0, len(listings))
+	for _, l := range listings {
+		d := distances[strings.ToLower(l.Market.SystemName)]
+		views = append(views, listingView{Market: l.Market, Price: newPriceView(l.Price, human), Distance: &d})
+	}
+	writeJSON(w, views)
+}
+
+// GET /systems/{name}/nearby?radius=&limit=
+func (s *Server) nearbySystems(w http.ResponseWriter, r *http.Request, name string) {
+	from, ok := s.systems.ByName(name)
+	if !ok {
+		httpError(w, http.StatusNotFound, "system not found")
+		return
+	}
+	_, limit, err := parseFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var systems []galaxy.System
+	if v := r.URL.Query().Get("radius"); v != "" {
+		radius, err := strconv.ParseFloat(v, 64)
+		if err != nil || radius <= 0 {
+			httpError(w, http.StatusBadRequest, "invalid radius")
+			return
+		}
+		systems = s.systems.Within(from.Pos, radius)
+		if len(systems) > limit {
+			systems = systems[:limit]
+		}
+	} else {
+		systems = s.systems.Nearest(from.Pos, limit)
+	}
+
+	type nearby struct {
+		galaxy.System
+		Distance float64 `json:"distance"`
+	}
+	out := make([]nearby, 0, len(systems))
+	for _, sys := range systems {
+		out = append(out, nearby{sys, galaxy.Distance(from.Pos, sys.Pos)})
+	}
+	writeJSON(w, out)
+}
+
+// GET /markets/{marketId}
+func (s *Server) market(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/markets/")
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, "invalid market id")
+		return
+	}
+
+	m, prices, err := s.store.Market(id)
+	if store.IsNotFound(err) {
+		httpError(w, http.StatusNotFound, "market not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	human := wantHuman(r)
+	views := make([]priceView, 0, len(prices))
+	for _, p := range prices {
+		views = append(views, newPriceView(p, human))
+	}
+	writeJSON(w, struct {
+		Market      *store.Market `json:"market"`
+		Commodities []priceView   `json:"commodities"`
+	}{m, views})
+}
+
+// GET /systems/{name}/markets, /systems/{name}/nearby and
+// /systems/{name}/stations
+func (s *Server) systemMarkets(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/systems/")
+	if len(parts) == 2 && parts[1] == "nearby" {
+		s.nearbySystems(w, r, parts[0])
+		return
+	}
+	if len(parts) == 2 && parts[1] == "stations" {
+		s.systemStations(w, r, parts[0])
+		return
+	}
+	if len(parts) != 2 || parts[1] != "markets" {
+		http.NotFound(w, r)
+		return
+	}
+	f, _, err := parseFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	markets, err := s.store.SystemMarkets(parts[0], f)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if markets == nil {
+		markets = []store.Market{}
+	}
+	writeJSON(w, markets)
+}
+
+// GET /modules/{symbol} and /ships/{symbol}, optionally ?from={system} to
+// list the nearest stations instead of the most recently seen.
+func (s *Server) stockists(kind string, name func(string) string) http.HandlerFunc {
+	prefix := "/" + kind + "s/"
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := pathParts(r, prefix)
+		if len(parts) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+		_, limit, err := parseFilter(r.URL.Query())
+		if err != nil {
+			httpError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var stockists []store.Stockist
+		if v := r.URL.Query().Get("from"); v != "" {
+			from, ok := s.systems.ByName(v)
+			if !ok {
+				httpError(w, http.StatusBadRequest, "unknown from system")
+				return
+			}
+			stockists, err = s.store.NearestStockists(kind, parts[0], from.Pos, limit)
+		} else {
+			stockists, err = s.store.Stockists(kind, parts[0], limit)
+		}
+		if err != nil {
+			s.internalError(w, err)
+			return
+		}
+		if stockists == nil {
+			stockists = []store.Stockist{}
+		}
+		writeJSON(w, struct {
+			Symbol   string           `json:"symbol"`
+			Name     string           `json:"name"`
+			Stations []store.Stockist `json:"stations"`
+		}{strings.ToLower(parts[0]), name(parts[0]), stockists})
+	}
+}
+
+// GET /systems/{name}/stations?service=&large_pads=
+func (s *Server) systemStations(w http.ResponseWriter, r *http.Request, system string) {
+	q := stations.Query{Service: r.URL.Query().Get("service")}
+	if v := r.URL.Query().Get("large_pads"); v != "" {
+		large, err := strconv.ParseBool(v)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "invalid large_pads")
+			return
+		}
+		q.LargePads = large
+	}
+
+	list, err := s.store.StationsInSystem(system, q)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if list == nil {
+		list = []stations.Station{}
+	}
+	writeJSON(w, list)
+}
+
+type listingView struct {
+	Market   store.Market `json:"market"`
+	Price    priceView    `json:"price"`
+	Distance *float64     `json:"distance,omitempty"`
+}
+
+// priceView adds formatted credit amounts when ?human=1 is requested.
+type priceView struct {
+	store.Price
+	MeanPriceText string `json:"meanPriceText,omitempty"`
+	BuyPriceText  string `json:"buyPriceText,omitempty"`
+	SellPriceText string `json:"sellPriceText,omitempty"`
+}
+
+func newPriceView(p store.Price, human bool) priceView {
+	v := priceView{Price: p}
+	if human {
+		v.MeanPriceText = formatCurrency(int(p.MeanPrice))
+		v.BuyPriceText = formatCurrency(int(p.BuyPrice))
+		v.SellPriceText = formatCurrency(int(p.SellPrice))
+	}
+	return v
+}
+
+// parseFilter reads max_age, pad, station_type, carrier and limit.
+func parseFilter(q url.Values) (store.Filter, int, error) {
+	var f store.Filter
+	if v := q.Get("max_age"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return f, 0, fmt.Errorf("invalid max_age: %v", err)
+		}
+		f.MaxAge = d
+	}
+	switch pad := strings.ToUpper(q.Get("pad")); pad {
+	case "", "S", "M", "L":
+		f.Pad = pad
+	default:
+		return f, 0, fmt.Errorf("invalid pad %q, want S, M or L", pad)
+	}
+	f.StationType = q.Get("station_type")
+	switch q.Get("carrier") {
+	case "", "any":
+	case "only":
+		f.Carriers = store.OnlyCarriers
+	case "exclude":
+		f.Carriers = store.NoCarriers
+	default:
+		return f, 0, fmt.Errorf("invalid carrier %q, want any, only or exclude", q.Get("carrier"))
+	}
+
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, 0, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = n
+	}
+	return f, limit, nil
+}
+
+func wantHuman(r *http.Request) bool {
+	v, _ := strconv.ParseBool(r.URL.Query().Get("human"))
+	return v
+}
+
+// pathParts splits the path after prefix, unescaping each segment so
+// commodity and system names may contain spaces.
+func pathParts(r *http.Request, prefix string) []string {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+	if rest == "" {
+		return nil
+	}
+	parts := strings.Split(rest, "/")
+	for i, p := range parts {
+		if u, err := url.PathUnescape(p); err == nil {
+			parts[i] = u
+		}
+	}
+	return parts
+}
+
+func (s *Server) internalError(w http.ResponseWriter, err error) {
+	log.Printf("Error serving request: %v\n", err)
+	httpError(w, http.StatusInternalServerError, "internal error")
+}
+
+func httpError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing response: %v\n", err)
+	}
+}
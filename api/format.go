@@ -0,0 +1,23 @@
+package api
+
+import "strconv"
+
+func formatCurrency(amount int) string {
+	// Convert the integer to a string with commas
+	str := strconv.FormatInt(int64(amount), 10)
+	n := len(str)
+	if n <= 3 {
+		return "$" + str
+	}
+
+	// Add commas every three digits
+	result := ""
+	for i, c := range str {
+		if (n-i)%3 == 0 && i != 0 {
+			result += ","
+		}
+		result += string(c)
+	}
+
+	return "$" + result
+}
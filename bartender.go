@@ -0,0 +1,97 @@
+package main
+
+import (
+	"EDDN/bartender"
+	"EDDN/store"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+func bartenderOrders(args []string) {
+	fs := flag.NewFlagSet("bartender", flag.ExitOnError)
+	db := fs.String("db", "eddn.db", "SQLite database written by listen -db")
+	sell := fs.String("sell", "", "list carriers selling this material, cheapest first")
+	buy := fs.String("buy", "", "list carriers buying this material, best price first")
+	carrier := fs.String("carrier", "", "show the orders and history of this carrier ID")
+	limit := fs.Int("limit", 10, "number of carriers or history entries to show")
+	fs.Parse(args)
+
+	st, err := store.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	tracker := bartender.NewTracker()
+	if err := st.LoadCarriers(tracker); err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case *sell != "":
+		printOrders(tracker, tracker.Sellers(*sell), *limit)
+	case *buy != "":
+		printOrders(tracker, tracker.Buyers(*buy), *limit)
+	case *carrier != "":
+		c := tracker.Carrier(*carrier)
+		if c == nil {
+			log.Fatalf("bartender: carrier %s has never been seen", *carrier)
+		}
+		fmt.Printf("%s, updated %s\n", carrierLabel(c), c.Updated.Format(time.RFC3339))
+		printSide("Sells", c.Sells)
+		printSide("Buys", c.Buys)
+		history, err := st.CarrierHistory(c.ID, "", *limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(history) > 0 {
+			fmt.Println("History:")
+		}
+		for _, o := range history {
+			fmt.Printf("   %s %-4s %-30s %6d @ %d\n", o.Timestamp.Format(time.RFC3339), o.Side, o.Name, o.Count, o.Price)
+		}
+	default:
+		log.Fatal("bartender: one of -sell, -buy or -carrier is required")
+	}
+}
+
+func printOrders(tracker *bartender.Tracker, ml bartender.MatList, limit int) {
+	if len(ml) == 0 {
+		fmt.Println("No carriers found.")
+		return
+	}
+	for i, o := range ml {
+		if i == limit {
+			break
+		}
+		c := tracker.Carrier(o.Name)
+		fmt.Printf("%d. %s: %d @ %d cr (%s)\n", i+1, carrierLabel(c), o.Mat.Count, o.Mat.Price, c.Updated.Format(time.RFC3339))
+	}
+}
+
+func printSide(title string, orders map[string]bartender.Mat) {
+	if len(orders) == 0 {
+		return
+	}
+	names := make([]string, 0, len(orders))
+	for name := range orders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("%s:\n", title)
+	for _, name := range names {
+		fmt.Printf("   %-30s %6d @ %d\n", name, orders[name].Count, orders[name].Price)
+	}
+}
+
+// carrierLabel names a carrier by ID, plus its name when known; the CAPI
+// schema does not carry names.
+func carrierLabel(c *bartender.Carrier) string {
+	if c.Name == "" {
+		return c.ID
+	}
+	return c.ID + " " + c.Name
+}
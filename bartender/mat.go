@@ -0,0 +1,19 @@
+package bartender
+
+// Mat is one carrier's order for a material: how many units and at what
+// price per unit.
+type Mat struct {
+	Count int
+	Price int
+}
+
+// Sortable slice of Mat items. Name is the CarrierID holding the order.
+type MatList []struct {
+	Name string
+	Mat  Mat
+}
+
+// Implement sort.Interface for MatList based on the price field
+func (ml MatList) Len() int           { return len(ml) }
+func (ml MatList) Swap(i, j int)      { ml[i], ml[j] = ml[j], ml[i] }
+func (ml MatList) Less(i, j int) bool { return ml[i].Mat.Price < ml[j].Mat.Price }
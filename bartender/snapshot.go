@@ -0,0 +1,63 @@
+// Package bartender tracks the Odyssey micro-resource orders fleet carrier
+// bartenders post: what each carrier sells, what it buys and at what price.
+package bartender
+
+import (
+	"strings"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// Snapshot is one carrier's orders as reported by a single message. A
+// source that only reports one side leaves the other side unknown, and
+// applying the snapshot keeps whatever was known before.
+type Snapshot struct {
+	CarrierID   string
+	CarrierName string
+	MarketID    int64
+	Timestamp   time.Time
+
+	Sells, Buys           map[string]Mat
+	SellsKnown, BuysKnown bool
+}
+
+// FromMaterials converts an order list from either fcmaterials schema.
+func FromMaterials(m eddn.CarrierMaterialsSnapshot) Snapshot {
+	return Snapshot{
+		CarrierID:   m.CarrierID,
+		CarrierName: m.CarrierName,
+		MarketID:    int64(m.MarketID),
+		Timestamp:   m.Timestamp.Time,
+		Sells:       orders(m.Sales),
+		Buys:        orders(m.Purchases),
+		SellsKnown:  m.Sales != nil,
+		BuysKnown:   m.Purchases != nil,
+	}
+}
+
+func orders(list []eddn.CarrierMaterial) map[string]Mat {
+	m := make(map[string]Mat, len(list))
+	for _, o := range list {
+		if o.Count > 0 {
+			m[Normalize(o.Name)] = Mat{Count: int(o.Count), Price: int(o.Price)}
+		}
+	}
+	return m
+}
+
+// Normalize reduces the different spellings of a material, such as the
+// journal's "$iondistributor_name;", the API's "IonDistributor" and a
+// user's "Ion Distributor", to the same key.
+func Normalize(name string) string {
+	name = strings.ToLower(name)
+	name = strings.TrimPrefix(name, "$")
+	name = strings.TrimSuffix(name, "_name;")
+	var b strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package bartender
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Carrier is the latest known state of one carrier's bartender.
+type Carrier struct {
+	ID       string
+	Name     string
+	MarketID int64
+	Updated  time.Time
+	Sells    map[string]Mat
+	Buys     map[string]Mat
+}
+
+// Tracker holds the current orders of every carrier seen. It is safe for
+// concurrent use.
+type Tracker struct {
+	mu       sync.RWMutex
+	carriers map[string]*Carrier
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{carriers: make(map[string]*Carrier)}
+}
+
+// Apply merges a snapshot. Snapshots older than what is already known for
+// the carrier are ignored, and it reports whether anything changed.
+func (t *Tracker) Apply(s Snapshot) bool {
+	if s.CarrierID == "" {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.carriers[s.CarrierID]
+	if !ok {
+		c = &Carrier{ID: s.CarrierID, Sells: make(map[string]Mat), Buys: make(map[string]Mat)}
+		t.carriers[s.CarrierID] = c
+	} else if s.Timestamp.Before(c.Updated) {
+		return false
+	}
+	if s.CarrierName != "" {
+		c.Name = s.CarrierName
+	}
+	if s.MarketID != 0 {
+		c.MarketID = s.MarketID
+	}
+	c.Updated = s.Timestamp
+	if s.SellsKnown {
+		c.Sells = s.Sells
+	}
+	if s.BuysKnown {
+		c.Buys = s.Buys
+	}
+	return true
+}
+
+// Carrier returns a copy of what is known about a carrier, or nil.
+func (t *Tracker) Carrier(id string) *Carrier {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	c, ok := t.carriers[id]
+	if !ok {
+		return nil
+	}
+	cp := *c
+	return &cp
+}
+
+// Sellers lists the carriers selling material, cheapest first.
+func (t *Tracker) Sellers(material string) MatList {
+	ml := t.orders(material, func(c *Carrier) map[string]Mat { return c.Sells })
+	sort.Stable(ml)
+	return ml
+}
+
+// Buyers lists the carriers buying material, best price first.
+func (t *Tracker) Buyers(material string) MatList {
+	ml := t.orders(material, func(c *Carrier) map[string]Mat { return c.Buys })
+	sort.Stable(sort.Reverse(ml))
+	return ml
+}
+
+func (t *Tracker) orders(material string, side func(*Carrier) map[string]Mat) MatList {
+	name := Normalize(material)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var ml MatList
+	for id, c := range t.carriers {
+		if m, ok := side(c)[name]; ok {
+			ml = append(ml, struct {
+				Name string
+				Mat  Mat
+			}{id, m})
+		}
+	}
+	// Map order is random; settle ties by carrier ID.
+	sort.Slice(ml, func(i, j int) bool { return ml[i].Name < ml[j].Name })
+	return ml
+}
@@ -0,0 +1,115 @@
+// Package capture reads and writes files of raw EDDN relay traffic so it can
+// be replayed through the decoder offline.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the on-disk layout of a capture file.
+type Format int
+
+const (
+	// Frames stores each zlib frame exactly as it came off the socket,
+	// prefixed with its length as a big-endian uint32.
+	Frames Format = iota
+	// JSONLines stores one decompressed EDDN message per line.
+	JSONLines
+)
+
+// maxFrame guards against reading a corrupt length prefix as a huge frame.
+const maxFrame = 16 << 20
+
+// FormatFor guesses the format of a capture file from its name, looking
+// past a trailing .gz or .zst.
+func FormatFor(path string) Format {
+	path = strings.ToLower(path)
+	path = strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ".zst")
+	switch filepath.Ext(path) {
+	case ".jsonl", ".ndjson", ".json":
+		return JSONLines
+	}
+	return Frames
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "frames":
+		return Frames, nil
+	case "jsonl":
+		return JSONLines, nil
+	}
+	return 0, fmt.Errorf("unknown capture format %q", s)
+}
+
+// Reader returns the records of a capture file one at a time.
+type Reader struct {
+	r      *bufio.Reader
+	format Format
+}
+
+func NewReader(r io.Reader, format Format) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, 1<<16), format: format}
+}
+
+// Next returns the next record, or io.EOF once the file is exhausted.
+func (r *Reader) Next() ([]byte, error) {
+	if r.format == JSONLines {
+		return r.nextLine()
+	}
+
+	var size uint32
+	if err := binary.Read(r.r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > maxFrame {
+		return nil, fmt.Errorf("capture frame of %d bytes exceeds limit", size)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r.r, frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (r *Reader) nextLine() ([]byte, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Writer appends frames to a capture file in the Frames format.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Write(frame []byte) error {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(frame)))
+	if _, err := w.w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(frame)
+	return err
+}
@@ -0,0 +1,328 @@
+package capture
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"EDDN/eddn"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects how archive files are compressed on disk.
+type Compression int
+
+const (
+	Gzip Compression = iota
+	Zstd
+)
+
+func ParseCompression(s string) (Compression, error) {
+	switch s {
+	case "gzip", "gz":
+		return Gzip, nil
+	case "zstd", "zst":
+		return Zstd, nil
+	}
+	return 0, fmt.Errorf("unknown compression %q", s)
+}
+
+func (c Compression) ext() string {
+	if c == Zstd {
+		return ".zst"
+	}
+	return ".gz"
+}
+
+// IndexFile is the name of the per-directory archive index. Each line is an
+// IndexEntry. A file gets one entry once its first frame is written and
+// another when it is closed, so the last entry for a file is the current
+// one; a zero Closed means the file is still being written.
+const IndexFile = "index.jsonl"
+
+// IndexEntry describes one archive file.
+type IndexEntry struct {
+	File         string    `json:"file"`
+	Opened       time.Time `json:"opened"`
+	Closed       time.Time `json:"closed"`
+	Frames       int       `json:"frames"`
+	Bytes        int64     `json:"bytes"`
+	FirstGateway time.Time `json:"firstGatewayTimestamp"`
+	LastGateway  time.Time `json:"lastGatewayTimestamp"`
+}
+
+// Recorder archives raw relay frames to compressed capture files in Dir,
+// starting a new file every hour and whenever the current one grows past
+// MaxBytes on disk.
+type Recorder struct {
+	Dir         string
+	Compression Compression
+	MaxBytes    int64
+
+	mu    sync.Mutex
+	f     *os.File
+	count *countingWriter
+	zw    io.WriteCloser
+	w     *Writer
+	hour  time.Time
+	entry IndexEntry
+}
+
+// NewRecorder returns a Recorder archiving to dir. Archive files left
+// unfinished by an earlier run that did not close cleanly are indexed from
+// their contents first.
+func NewRecorder(dir string, compression Compression, maxBytes int64) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	r := &Recorder{Dir: dir, Compression: compression, MaxBytes: maxBytes}
+	if err := r.recover(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadIndex returns the current IndexEntry of every file in dir's index, in
+// the order the files were first indexed.
+func ReadIndex(dir string) ([]IndexEntry, error) {
+	f, err := os.Open(filepath.Join(dir, IndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []IndexEntry
+	seen := make(map[string]int)
+	dec := json.NewDecoder(f)
+	for {
+		var e IndexEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+		if i, ok := seen[e.File]; ok {
+			entries[i] = e
+			continue
+		}
+		seen[e.File] = len(entries)
+		entries = append(entries, e)
+	}
+}
+
+// recover indexes archive files in Dir that were never closed, reading them
+// back to count their frames. Their compressed stream ends abruptly, so
+// they are read up to the last whole frame.
+func (r *Recorder) recover() error {
+	entries, err := ReadIndex(r.Dir)
+	if err != nil {
+		return err
+	}
+	indexed := make(map[string]IndexEntry, len(entries))
+	for _, e := range entries {
+		indexed[e.File] = e
+	}
+
+	var paths []string
+	for _, pattern := range []string{"eddn-*.frames.gz", "eddn-*.frames.zst"} {
+		matches, err := filepath.Glob(filepath.Join(r.Dir, pattern))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		e, ok := indexed[filepath.Base(path)]
+		if ok && !e.Closed.IsZero() {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			e = IndexEntry{File: filepath.Base(path), Opened: openedAt(path)}
+		}
+		e.Frames, e.FirstGateway, e.LastGateway = 0, time.Time{}, time.Time{}
+
+		frames := make(chan []byte)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- ReadFile(context.Background(), path, Frames, frames)
+			close(frames)
+		}()
+		for frame := range frames {
+			e.observe(frame)
+		}
+		// The truncated tail is expected, it is what the crash left.
+		<-errc
+
+		e.Closed = info.ModTime().UTC()
+		e.Bytes = info.Size()
+		if err := r.appendIndex(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write appends frame to the current archive file, rotating first if needed.
+func (r *Recorder) Write(frame []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	hour := now.Truncate(time.Hour)
+	if r.f != nil && (!hour.Equal(r.hour) || (r.MaxBytes > 0 && r.count.n >= r.MaxBytes)) {
+		if err := r.closeFile(now); err != nil {
+			return err
+		}
+	}
+	if r.f == nil {
+		if err := r.openFile(now); err != nil {
+			return err
+		}
+	}
+
+	if err := r.w.Write(frame); err != nil {
+		return err
+	}
+	r.entry.observe(frame)
+
+	// Index the file as soon as it has a first timestamp, and get that
+	// frame to disk, so it can be found even if it is never closed.
+	if r.entry.Frames == 1 {
+		if f, ok := r.zw.(interface{ Flush() error }); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+		return r.appendIndex(r.entry)
+	}
+	return nil
+}
+
+// observe counts frame and widens the gateway timestamp range to cover it.
+// Frames that cannot be decoded are still archived, they just do not
+// contribute to the range.
+func (e *IndexEntry) observe(frame []byte) {
+	e.Frames++
+	h, err := eddn.PeekHeader(frame)
+	if err != nil || h.GatewayTimestamp.IsZero() {
+		return
+	}
+	ts := h.GatewayTimestamp
+	if e.FirstGateway.IsZero() || ts.Before(e.FirstGateway) {
+		e.FirstGateway = ts
+	}
+	if ts.After(e.LastGateway) {
+		e.LastGateway = ts
+	}
+}
+
+// Close finishes the current archive file and records it in the index.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	return r.closeFile(time.Now().UTC())
+}
+
+// fileStamp is the layout of the time in archive file names.
+const fileStamp = "20060102T150405"
+
+func (r *Recorder) openFile(now time.Time) error {
+	base := "eddn-" + now.Format(fileStamp) + ".frames" + r.Compression.ext()
+	path := filepath.Join(r.Dir, base)
+	// Several rotations can happen within a second when MaxBytes is small.
+	for i := 1; fileExists(path); i++ {
+		path = filepath.Join(r.Dir, fmt.Sprintf("eddn-%s-%d.frames%s", now.Format(fileStamp), i, r.Compression.ext()))
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	count := &countingWriter{w: f}
+
+	var zw io.WriteCloser
+	switch r.Compression {
+	case Zstd:
+		zw, err = zstd.NewWriter(count)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	default:
+		zw = gzip.NewWriter(count)
+	}
+
+	r.f, r.count, r.zw, r.w = f, count, zw, NewWriter(zw)
+	r.hour = now.Truncate(time.Hour)
+	r.entry = IndexEntry{File: filepath.Base(path), Opened: now}
+	return nil
+}
+
+func (r *Recorder) closeFile(now time.Time) error {
+	err := r.zw.Close()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	r.entry.Closed = now
+	r.entry.Bytes = r.count.n
+	if ierr := r.appendIndex(r.entry); err == nil {
+		err = ierr
+	}
+	r.f, r.count, r.zw, r.w = nil, nil, nil, nil
+	return err
+}
+
+func (r *Recorder) appendIndex(e IndexEntry) error {
+	f, err := os.OpenFile(filepath.Join(r.Dir, IndexFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(e)
+}
+
+// openedAt recovers when an archive file was opened from its name.
+func openedAt(path string) time.Time {
+	stamp := strings.TrimPrefix(filepath.Base(path), "eddn-")
+	if len(stamp) < len(fileStamp) {
+		return time.Time{}
+	}
+	t, _ := time.Parse(fileStamp, stamp[:len(fileStamp)])
+	return t
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,178 @@
+package capture
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// paddedFrame returns a plain JSON frame with gateway timestamp base+i
+// seconds and a few kilobytes of incompressible padding, so the compressed
+// archive grows with every frame.
+func paddedFrame(r *rand.Rand, i int) []byte {
+	pad := make([]byte, 3000)
+	r.Read(pad)
+	return []byte(fmt.Sprintf(`{"$schemaRef": "https://eddn.edcd.io/schemas/journal/1",
+		"header": {"uploaderID": "test", "softwareName": "test", "softwareVersion": "1", "gatewayTimestamp": %q},
+		"message": {"pad": %q}}`, base.Add(time.Duration(i)*time.Second).Format(time.RFC3339), base64.StdEncoding.EncodeToString(pad)))
+}
+
+// readArchive returns the gateway timestamps of the frames in an archive
+// file, up to the first that cannot be read.
+func readArchive(t *testing.T, path string) []time.Time {
+	t.Helper()
+	frames := make(chan []byte)
+	go func() {
+		ReadFile(context.Background(), path, Frames, frames)
+		close(frames)
+	}()
+	var out []time.Time
+	for frame := range frames {
+		h, err := eddn.PeekHeader(frame)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		out = append(out, h.GatewayTimestamp)
+	}
+	return out
+}
+
+func TestRecorderRecovery(t *testing.T) {
+	for _, compression := range []Compression{Gzip, Zstd} {
+		dir := t.TempDir()
+		r, err := NewRecorder(dir, compression, 64<<10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Write across a few rotations, stopping once the compressor has
+		// put a good part of the current file on disk.
+		rnd := rand.New(rand.NewSource(1))
+		var before []IndexEntry
+		n := 0
+		for ; n < 1000; n++ {
+			if err := r.Write(paddedFrame(rnd, n)); err != nil {
+				t.Fatal(err)
+			}
+			if n < 100 {
+				continue
+			}
+			if before, err = ReadIndex(dir); err != nil {
+				t.Fatal(err)
+			}
+			if st, err := os.Stat(filepath.Join(dir, before[len(before)-1].File)); err == nil && st.Size() > 40<<10 {
+				n++
+				break
+			}
+		}
+
+		// Crash: the current file is never closed, and loses its tail.
+		if len(before) < 3 {
+			t.Fatalf("%d archive files, want a few rotations", len(before))
+		}
+		open := before[len(before)-1]
+		if !open.Closed.IsZero() || open.Frames != 1 {
+			t.Fatalf("open file indexed as %+v, want its first frame and no close time", open)
+		}
+		for _, e := range before[:len(before)-1] {
+			if e.Closed.IsZero() {
+				t.Fatalf("rotated file %s not closed in the index", e.File)
+			}
+		}
+		last := filepath.Join(dir, open.File)
+		info, err := os.Stat(last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Truncate(last, info.Size()-info.Size()/4); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewRecorder(dir, compression, 64<<10); err != nil {
+			t.Fatal(err)
+		}
+		after, err := ReadIndex(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(after) != len(before) {
+			t.Fatalf("%d files indexed after recovery, want %d", len(after), len(before))
+		}
+
+		// Replaying the files in index order gives the frames in the order
+		// they were recorded, and each entry describes its file.
+		next := 0
+		for i, e := range after {
+			if e.Closed.IsZero() {
+				t.Errorf("%s still open after recovery", e.File)
+			}
+			got := readArchive(t, filepath.Join(dir, e.File))
+			if e.Frames != len(got) {
+				t.Fatalf("%s: index says %d frames, file has %d", e.File, e.Frames, len(got))
+			}
+			if len(got) == 0 {
+				t.Fatalf("%s: no frames recovered", e.File)
+			}
+			for _, ts := range got {
+				if want := base.Add(time.Duration(next) * time.Second); !ts.Equal(want) {
+					t.Fatalf("%s: frame at %v, want %v", e.File, ts, want)
+				}
+				next++
+			}
+			if !e.FirstGateway.Equal(got[0]) || !e.LastGateway.Equal(got[len(got)-1]) {
+				t.Errorf("%s: index range %v to %v, file has %v to %v", e.File, e.FirstGateway, e.LastGateway, got[0], got[len(got)-1])
+			}
+			if i == len(after)-1 {
+				if st, _ := os.Stat(filepath.Join(dir, e.File)); e.Bytes != st.Size() {
+					t.Errorf("%s: index says %d bytes, file has %d", e.File, e.Bytes, st.Size())
+				}
+			}
+		}
+		// The truncated tail, and whatever was still buffered, is lost.
+		if next == 0 || next >= n {
+			t.Errorf("recovered %d of %d frames, want some lost to the crash", next, n)
+		}
+
+		// A second start finds nothing left to recover.
+		if _, err := NewRecorder(dir, compression, 64<<10); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, IndexFile))
+		again, _ := ReadIndex(dir)
+		if len(again) != len(after) || again[len(again)-1] != after[len(after)-1] {
+			t.Errorf("index changed on a clean restart:\n%s", data)
+		}
+	}
+}
+
+func TestRecorderClose(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder(dir, Gzip, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 10; i++ {
+		r.Write(paddedFrame(rnd, i))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadIndex(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("index %+v, %v", entries, err)
+	}
+	e := entries[0]
+	if e.Frames != 10 || e.Closed.IsZero() || !e.LastGateway.Equal(base.Add(9*time.Second)) {
+		t.Errorf("closed file indexed as %+v", e)
+	}
+	if got := readArchive(t, filepath.Join(dir, e.File)); len(got) != 10 {
+		t.Errorf("closed file holds %d frames, want 10", len(got))
+	}
+}
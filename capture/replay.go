@@ -0,0 +1,88 @@
+package capture
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"EDDN/eddn"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ReadFile sends every record in the capture file at path to out. Files
+// ending in .gz or .zst, as written by Recorder, are decompressed on the fly.
+func ReadFile(ctx context.Context, path string, format Format, out chan<- []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		src = zr
+	case strings.HasSuffix(path, ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		src = zr
+	}
+
+	r := NewReader(src, format)
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- rec:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Pace forwards envelopes from in to out, spacing them by the gaps between
+// their gateway timestamps divided by speed. A speed of 0 or less forwards
+// them as fast as possible. out is closed once in is drained.
+func Pace(ctx context.Context, speed float64, in <-chan *eddn.Envelope, out chan<- *eddn.Envelope) {
+	defer close(out)
+
+	var first time.Time
+	var start time.Time
+	for env := range in {
+		ts := env.Header.GatewayTimestamp
+		if speed > 0 && !ts.IsZero() {
+			if first.IsZero() {
+				first, start = ts, time.Now()
+			}
+			due := start.Add(time.Duration(float64(ts.Sub(first)) / speed))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		select {
+		case out <- env:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
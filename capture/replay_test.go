@@ -0,0 +1,99 @@
+package capture
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"EDDN/eddn"
+)
+
+var base = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// frame returns a zlib frame of the given schema family, commodity or
+// journal, with a gateway timestamp i seconds after base. Commodity frames
+// carry size commodities so they take longer to decode.
+func frame(t *testing.T, family string, i, size int) []byte {
+	t.Helper()
+	gateway := base.Add(time.Duration(i) * time.Second).Format(time.RFC3339)
+	var msg string
+	switch family {
+	case "commodity":
+		items := make([]string, size)
+		for j := range items {
+			items[j] = fmt.Sprintf(`{"name": "c%d", "meanPrice": 1, "buyPrice": 1, "stock": 1, "stockBracket": 1,
+				"sellPrice": 1, "demand": 1, "demandBracket": 1}`, j)
+		}
+		msg = fmt.Sprintf(`{"$schemaRef": "https://eddn.edcd.io/schemas/commodity/3",
+			"header": {"uploaderID": "test", "softwareName": "test", "softwareVersion": "1", "gatewayTimestamp": %q},
+			"message": {"systemName": "Sol", "stationName": "%d", "marketId": 1, "timestamp": %q,
+				"commodities": [%s]}}`, gateway, i, gateway, strings.Join(items, ","))
+	default:
+		msg = fmt.Sprintf(`{"$schemaRef": "https://eddn.edcd.io/schemas/journal/1",
+			"header": {"uploaderID": "test", "softwareName": "test", "softwareVersion": "1", "gatewayTimestamp": %q},
+			"message": {"event": "Docked", "StarSystem": "Sol", "StarPos": [0, 0, 0], "SystemAddress": 10477373803,
+				"StationName": "%d", "MarketID": 1, "timestamp": %q}}`, gateway, i, gateway)
+	}
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(msg))
+	zw.Close()
+	return buf.Bytes()
+}
+
+// TestReplayOrder replays a capture of interleaved schemas the way the
+// replay command does and checks the envelopes come out as recorded.
+func TestReplayOrder(t *testing.T) {
+	const n = 150
+	path := filepath.Join(t.TempDir(), "mixed.frames")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWriter(f)
+	for i := 0; i < n; i++ {
+		// Big commodity frames next to small journal ones, so a pool of
+		// workers finishes them out of order.
+		family, size := "journal", 0
+		if i%3 == 0 {
+			family, size = "commodity", 100
+		}
+		if err := w.Write(frame(t, family, i, size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	frames := make(chan []byte)
+	go func() {
+		defer close(frames)
+		if err := ReadFile(ctx, path, FormatFor(path), frames); err != nil {
+			t.Error(err)
+		}
+	}()
+	client := &eddn.Client{Workers: 8, Ordered: true, OrderBy: eddn.OneSequence}
+	decoded := make(chan *eddn.Envelope)
+	go client.Consume(ctx, frames, decoded)
+	envelopes := make(chan *eddn.Envelope)
+	go Pace(ctx, 0, decoded, envelopes)
+
+	i := 0
+	for env := range envelopes {
+		if want := base.Add(time.Duration(i) * time.Second); !env.Header.GatewayTimestamp.Equal(want) {
+			t.Fatalf("envelope %d (%s) has gateway timestamp %v, want %v", i, env.Schema.Family, env.Header.GatewayTimestamp, want)
+		}
+		i++
+	}
+	if i != n {
+		t.Errorf("replayed %d envelopes, want %d", i, n)
+	}
+}
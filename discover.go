@@ -0,0 +1,164 @@
+package main
+
+import (
+	"EDDN/capture"
+	"EDDN/discover"
+	"EDDN/eddn"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func discoverSchema(args []string) {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	schema := fs.String("schema", "", "schema to sample: a $schemaRef, family/version or family, e.g. commodity/3")
+	event := fs.String("event", "", "only sample messages with this event, e.g. FSDJump")
+	samples := fs.Int("n", 500, "messages to sample")
+	timeout := fs.Duration("timeout", 10*time.Minute, "stop sampling the relay after this long")
+	relay := fs.String("relay", eddn.DefaultRelay, "EDDN relay endpoint, used when no capture files are given")
+	name := fs.String("name", "", "name for the proposed struct (default: derived from the schema and event)")
+	fs.Parse(args)
+
+	family, version, err := parseSchemaFlag(*schema)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	frames := make(chan []byte)
+	if fs.NArg() > 0 {
+		go func() {
+			defer close(frames)
+			for _, path := range fs.Args() {
+				if err := capture.ReadFile(ctx, path, capture.FormatFor(path), frames); err != nil {
+					log.Printf("Error reading capture %s: %v\n", path, err)
+				}
+			}
+		}()
+	} else {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+		sub := eddn.NewSubscriber(*relay)
+		go func() {
+			sub.Run(ctx, frames)
+			close(frames)
+		}()
+		fmt.Fprintf(os.Stderr, "Sampling %d messages from %s...\n", *samples, *relay)
+	}
+
+	var sampler discover.Sampler
+	var matched eddn.Schema
+	for frame := range frames {
+		data, err := eddn.Unpack(frame)
+		if err != nil {
+			continue
+		}
+		var doc struct {
+			SchemaRef string          `json:"$schemaRef"`
+			Message   json.RawMessage `json:"message"`
+		}
+		if json.Unmarshal(data, &doc) != nil {
+			continue
+		}
+		s, ok := eddn.ParseSchemaRef(doc.SchemaRef)
+		if !ok || s.Family != family || (version != 0 && s.Version != version) {
+			continue
+		}
+		if *event != "" {
+			var ev struct {
+				Event string `json:"event"`
+			}
+			if json.Unmarshal(doc.Message, &ev) != nil || ev.Event != *event {
+				continue
+			}
+		}
+		if err := sampler.Add(doc.Message); err != nil {
+			continue
+		}
+		matched = s.Live()
+		if sampler.Samples >= *samples {
+			stop()
+			break
+		}
+	}
+	if sampler.Samples == 0 {
+		log.Fatal("discover: no matching messages")
+	}
+
+	existing := existingType(matched, *event)
+	structName := *name
+	if structName == "" {
+		structName = discover.GoName(family) + "Message"
+		if *event != "" {
+			structName = discover.GoName(*event) + "Event"
+		}
+		if existing != nil {
+			structName = existing.Name()
+		}
+	}
+
+	src, err := sampler.Struct(structName)
+	if err != nil {
+		log.Printf("Error formatting struct: %v\n", err)
+	}
+	fmt.Printf("// Proposed from %d %s messages.\n%s", sampler.Samples, matched, src)
+
+	if existing == nil {
+		fmt.Println("// No existing struct to compare with.")
+		return
+	}
+	lines := sampler.Diff(existing)
+	if len(lines) == 0 {
+		fmt.Printf("// eddn.%s matches what was observed.\n", existing.Name())
+		return
+	}
+	fmt.Printf("// Differences from eddn.%s:\n", existing.Name())
+	for _, l := range lines {
+		fmt.Println("// " + l)
+	}
+}
+
+// parseSchemaFlag accepts a full $schemaRef, "family/version" or "family".
+// A zero version matches every version.
+func parseSchemaFlag(v string) (string, int, error) {
+	if v == "" {
+		return "", 0, fmt.Errorf("discover: -schema is required")
+	}
+	if s, ok := eddn.ParseSchemaRef(v); ok {
+		return s.Family, s.Version, nil
+	}
+	family, version, found := strings.Cut(v, "/")
+	if !found {
+		return family, 0, nil
+	}
+	n, err := strconv.Atoi(version)
+	if err != nil {
+		return "", 0, fmt.Errorf("discover: bad schema version %q", version)
+	}
+	return family, n, nil
+}
+
+// existingType is the struct sampled messages currently decode into: the
+// typed journal event when one is asked for, otherwise the schema's struct.
+func existingType(s eddn.Schema, event string) reflect.Type {
+	if event != "" {
+		if ev := eddn.NewJournalEvent(event); ev != nil {
+			return reflect.TypeOf(ev).Elem()
+		}
+	}
+	if msg := eddn.NewMessage(s); msg != nil {
+		return reflect.TypeOf(msg).Elem()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package discover
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"EDDN/eddn"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// Diff compares the sampled shape with the struct type t, one line per
+// difference. Each line starts with a marker:
+//
+//	mark path  meaning
+//	+    path  observed but not declared
+//	-    path  declared but never observed
+//	~    path  declared with a type that cannot hold what was observed
+func (s *Sampler) Diff(t reflect.Type) []string {
+	var lines []string
+	diff(&s.Root, t, "", &lines)
+	return lines
+}
+
+func diff(s *Shape, t reflect.Type, path string, lines *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s.Kinds&Array != 0 && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && s.Elem != nil {
+		diff(s.Elem, t.Elem(), path+"[]", lines)
+		return
+	}
+	if s.Kinds&Object == 0 || t.Kind() != reflect.Struct || t == timeType {
+		return
+	}
+
+	declared := eddn.JSONFields(t)
+	seen := make(map[string]bool)
+	for _, f := range sortedFields(s) {
+		p := join(path, f.Name)
+		key := strings.ToLower(f.Name)
+		seen[key] = true
+		d, ok := declared[key]
+		if !ok {
+			*lines = append(*lines, fmt.Sprintf("+ %s %s", p, (&generator{names: map[string]bool{}}).goType(GoName(f.Name), f.Shape)))
+			continue
+		}
+		if !fits(f.Shape.Kinds, d.Type) {
+			*lines = append(*lines, fmt.Sprintf("~ %s declared %s, observed %s", p, d.Type, f.Shape.Kinds))
+		}
+		diff(f.Shape, d.Type, p, lines)
+	}
+
+	var missing []string
+	for key := range declared {
+		if !seen[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		d := declared[key]
+		*lines = append(*lines, fmt.Sprintf("- %s %s", join(path, d.Name), d.Type))
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fits reports whether a Go value of type t can hold every observed kind.
+func fits(k Kind, t reflect.Type) bool {
+	k &^= Null
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var allowed Kind
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Bool:
+		allowed = Bool
+	case reflect.String:
+		allowed = String
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		allowed = Int
+	case reflect.Float32, reflect.Float64:
+		allowed = Int | Float
+	case reflect.Slice, reflect.Array:
+		allowed = Array
+	case reflect.Map:
+		allowed = Object
+	case reflect.Struct:
+		allowed = Object
+		if t == timeType {
+			allowed = String
+		}
+	}
+	// Types with their own decoding are trusted.
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return true
+	}
+	return k&^allowed == 0
+}
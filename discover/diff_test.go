@@ -0,0 +1,50 @@
+package discover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type diffBase struct {
+	Timestamp string `json:"timestamp"`
+}
+
+type diffTarget struct {
+	diffBase
+	StarSystem string `json:"StarSystem"`
+	Population int64  `json:"Population"`
+	Missing    string `json:"Missing,omitempty"`
+	Ignored    string `json:"-"`
+	Signals    []struct {
+		Name string `json:"Name"`
+	} `json:"Signals"`
+}
+
+func TestDiff(t *testing.T) {
+	var s Sampler
+	for _, msg := range []string{
+		`{"timestamp": "2024-05-01T10:00:00Z", "StarSystem": "Sol", "Population": "many", "Signals": [{"Name": "a", "Type": "b"}], "Extra": true}`,
+		`{"timestamp": "2024-05-01T10:00:01Z", "starsystem": "Sol", "Population": "some", "Signals": [], "Extra": false}`,
+	} {
+		if err := s.Add([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := strings.Join(s.Diff(reflect.TypeOf(diffTarget{})), "\n")
+	for _, want := range []string{
+		"+ Extra bool",
+		"+ Signals[].Type string",
+		"~ Population declared int64",
+		"- Missing string",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff lacks %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"timestamp", "StarSystem", "Ignored", "Signals[].Name"} {
+		if strings.Contains(got, " "+unwanted+" ") {
+			t.Errorf("diff reports %s:\n%s", unwanted, got)
+		}
+	}
+}
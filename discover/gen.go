@@ -0,0 +1,157 @@
+package discover
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Struct returns Go source for a struct named name, and one for every
+// nested object, that the sampled messages would decode into. Each field
+// is commented with how often it was seen, its numeric range and examples.
+func (s *Sampler) Struct(name string) (string, error) {
+	g := &generator{names: make(map[string]bool)}
+	g.object(name, &s.Root)
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return g.buf.String(), err
+	}
+	return string(src), nil
+}
+
+type generator struct {
+	buf     bytes.Buffer
+	pending []namedShape
+	names   map[string]bool
+}
+
+type namedShape struct {
+	name  string
+	shape *Shape
+}
+
+func (g *generator) object(name string, s *Shape) {
+	g.pending = append(g.pending, namedShape{name, s})
+	g.names[name] = true
+	for len(g.pending) > 0 {
+		n := g.pending[0]
+		g.pending = g.pending[1:]
+		g.write(n.name, n.shape)
+	}
+}
+
+func (g *generator) write(name string, s *Shape) {
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	for _, f := range sortedFields(s) {
+		goName := GoName(f.Name)
+		typ := g.goType(name+goName, f.Shape)
+		tag := f.Name
+		if f.Optional(s) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s `json:\"%s\"` // %s\n", goName, typ, tag, describe(f, s))
+	}
+	g.buf.WriteString("}\n\n")
+}
+
+// goType picks the Go type for s, queueing a struct for objects.
+func (g *generator) goType(name string, s *Shape) string {
+	kinds := s.Kinds &^ Null
+	switch kinds {
+	case 0:
+		return "interface{}"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	case Int:
+		return "int64"
+	case Int | Float, Float:
+		return "float64"
+	case Array:
+		if s.Elem == nil || s.Elem.Values == 0 {
+			return "[]interface{}"
+		}
+		return "[]" + g.goType(name, s.Elem)
+	case Object:
+		name = g.unique(name)
+		g.pending = append(g.pending, namedShape{name, s})
+		if s.Kinds&Null != 0 {
+			return "*" + name
+		}
+		return name
+	}
+	return "interface{}"
+}
+
+func (g *generator) unique(name string) string {
+	n := name
+	for i := 2; g.names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[n] = true
+	return n
+}
+
+func describe(f *Field, parent *Shape) string {
+	var parts []string
+	if f.Optional(parent) {
+		parts = append(parts, fmt.Sprintf("seen %d/%d", f.Seen, parent.Objects))
+	}
+	s := f.Shape
+	if kinds := s.Kinds &^ Null; kinds&(kinds-1) != 0 {
+		parts = append(parts, "mixed "+kinds.String())
+	}
+	if s.Kinds&Null != 0 {
+		parts = append(parts, "nullable")
+	}
+	if s.Kinds&(Int|Float) != 0 {
+		parts = append(parts, strconv.FormatFloat(s.Min, 'f', -1, 64)+" to "+strconv.FormatFloat(s.Max, 'f', -1, 64))
+	}
+	if len(s.Examples) > 0 {
+		parts = append(parts, "e.g. "+strings.Join(s.Examples, ", "))
+	}
+	if len(parts) == 0 {
+		return "always present"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func sortedFields(s *Shape) []*Field {
+	fields := make([]*Field, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+// GoName turns a JSON member name into an exported Go identifier,
+// e.g. "Age_MY" becomes "AgeMY" and "marketId" becomes "MarketID".
+func GoName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	n := b.String()
+	if strings.HasSuffix(n, "Id") {
+		n = strings.TrimSuffix(n, "Id") + "ID"
+	}
+	if n == "" || unicode.IsDigit(rune(n[0])) {
+		n = "X" + n
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+// Package discover infers the shape of EDDN messages from samples and
+// proposes Go structs for them.
+package discover
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Kind is a set of JSON value types.
+type Kind uint8
+
+const (
+	Null Kind = 1 << iota
+	Bool
+	Int
+	Float
+	String
+	Object
+	Array
+)
+
+func (k Kind) String() string {
+	var names []string
+	for _, n := range []struct {
+		k    Kind
+		name string
+	}{{Null, "null"}, {Bool, "bool"}, {Int, "int"}, {Float, "float"}, {String, "string"}, {Object, "object"}, {Array, "array"}} {
+		if k&n.k != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// maxExamples is how many distinct example values a Shape keeps.
+const maxExamples = 3
+
+// Shape is everything seen at one position in the sampled messages.
+type Shape struct {
+	Kinds Kind
+	// Values is how many values were merged here.
+	Values int
+	// Numeric range, valid when Kinds has Int or Float.
+	Min, Max float64
+	// Examples are distinct scalar values as JSON text.
+	Examples []string
+
+	// Objects is how many of the values were objects; a field seen fewer
+	// times than that is optional.
+	Objects int
+	Fields  map[string]*Field
+	// Elem merges every element of every array seen here.
+	Elem *Shape
+}
+
+// Field is an object member.
+type Field struct {
+	Name  string
+	Seen  int
+	Shape *Shape
+}
+
+// Optional reports whether f was missing from some of the objects in parent.
+func (f *Field) Optional(parent *Shape) bool {
+	return f.Seen < parent.Objects
+}
+
+func (s *Shape) merge(v interface{}) {
+	s.Values++
+	switch v := v.(type) {
+	case nil:
+		s.Kinds |= Null
+	case bool:
+		s.Kinds |= Bool
+		s.example(v)
+	case string:
+		s.Kinds |= String
+		s.example(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return
+		}
+		if s.Kinds&(Int|Float) == 0 || f < s.Min {
+			s.Min = f
+		}
+		if s.Kinds&(Int|Float) == 0 || f > s.Max {
+			s.Max = f
+		}
+		if strings.ContainsAny(string(v), ".eE") {
+			s.Kinds |= Float
+		} else {
+			s.Kinds |= Int
+		}
+		s.example(v)
+	case map[string]interface{}:
+		s.Kinds |= Object
+		s.Objects++
+		if s.Fields == nil {
+			s.Fields = make(map[string]*Field)
+		}
+		for name, fv := range v {
+			f, ok := s.Fields[name]
+			if !ok {
+				f = &Field{Name: name, Shape: &Shape{}}
+				s.Fields[name] = f
+			}
+			f.Seen++
+			f.Shape.merge(fv)
+		}
+	case []interface{}:
+		s.Kinds |= Array
+		if s.Elem == nil {
+			s.Elem = &Shape{}
+		}
+		for _, e := range v {
+			s.Elem.merge(e)
+		}
+	}
+}
+
+func (s *Shape) example(v interface{}) {
+	if len(s.Examples) >= maxExamples {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	text := string(b)
+	if len(text) > 60 {
+		text = text[:57] + "..."
+	}
+	for _, e := range s.Examples {
+		if e == text {
+			return
+		}
+	}
+	s.Examples = append(s.Examples, text)
+}
+
+// Sampler merges sampled messages into a single Shape.
+type Sampler struct {
+	Root Shape
+	// Samples is how many messages were added.
+	Samples int
+}
+
+// Add merges one message object.
+func (s *Sampler) Add(msg json.RawMessage) error {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	s.Root.merge(v)
+	s.Samples++
+	return nil
+}
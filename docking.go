@@ -0,0 +1,134 @@
+package main
+
+import (
+	"EDDN/store"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func dockingStats(args []string) {
+	fs := flag.NewFlagSet("docking", flag.ExitOnError)
+	db := fs.String("db", "eddn.db", "SQLite database written by listen -db")
+	since := fs.Duration("since", 24*time.Hour, "only count docking requests this recent")
+	station := fs.Int64("station", 0, "MarketID of one station to show in detail")
+	interval := fs.Duration("interval", time.Hour, "with -station, count traffic per interval of this length")
+	order := fs.String("sort", "traffic", "rank stations by traffic, denials (denial rate) or hostile (hostile denials)")
+	carriers := fs.String("carriers", "any", "any, only or exclude fleet carriers")
+	minRequests := fs.Int("min", 5, "with -sort denials, leave out stations with fewer requests")
+	limit := fs.Int("limit", 10, "number of stations to show")
+	asJSON := fs.Bool("json", false, "print statistics as JSON")
+	fs.Parse(args)
+
+	st, err := store.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	from := time.Now().Add(-*since)
+	if *station != 0 {
+		d, err := st.DockingHistory(*station, from, *interval)
+		if store.IsNotFound(err) {
+			fmt.Printf("No docking requests at %d in the last %s.\n", *station, *since)
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *asJSON {
+			printJSON(d)
+			return
+		}
+		printDocking(d)
+		for _, b := range d.Traffic {
+			fmt.Printf("   %s  %4d granted %4d denied\n", b.Start.UTC().Format(time.RFC3339), b.Granted, b.Denied)
+		}
+		return
+	}
+
+	f := store.DockingFilter{Since: from}
+	switch *carriers {
+	case "any":
+	case "only":
+		f.Carriers = store.OnlyCarriers
+	case "exclude":
+		f.Carriers = store.NoCarriers
+	default:
+		log.Fatalf("docking: invalid -carriers %q, want any, only or exclude", *carriers)
+	}
+	var rank store.DockingOrder
+	switch *order {
+	case "traffic":
+		rank = store.ByTraffic
+	case "denials":
+		rank = store.ByDenialRate
+		f.MinRequests = *minRequests
+	case "hostile":
+		rank = store.ByHostile
+	default:
+		log.Fatalf("docking: invalid -sort %q, want traffic, denials or hostile", *order)
+	}
+
+	list, err := st.BusiestStations(f, rank, *limit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		printJSON(list)
+		return
+	}
+	if len(list) == 0 {
+		fmt.Println("No docking requests found.")
+		return
+	}
+	for i := range list {
+		fmt.Printf("%d. ", i+1)
+		printDocking(&list[i])
+	}
+}
+
+func printDocking(d *store.DockingStats) {
+	fmt.Printf("%s (%d, %s): %d requests, %.0f%% denied\n", d.StationName, d.MarketID, orUnknown(d.StationType),
+		d.Requests(), 100*d.DenialRate())
+	if len(d.Reasons) > 0 {
+		fmt.Printf("   denied: %s\n", counts(d.Reasons))
+	}
+	if len(d.Pads) > 0 {
+		pads := make(map[string]int, len(d.Pads))
+		for pad, n := range d.Pads {
+			pads[fmt.Sprintf("%02d", pad)] = n
+		}
+		fmt.Printf("   pads:   %s\n", counts(pads))
+	}
+}
+
+// counts formats a breakdown largest first, e.g. "NoSpace 12, Hostile 3".
+func counts(m map[string]int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, m[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
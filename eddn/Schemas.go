@@ -1,4 +1,4 @@
-package main
+package eddn
 
 import (
 	"encoding/json"
@@ -21,14 +21,14 @@ type EDDNHeader struct {
 }
 
 type ApproachSettlementMessage struct {
-	Timestamp         string           `json:"timestamp"`
+	Timestamp         Time             `json:"timestamp"`
 	Event             string           `json:"event"`
 	StarSystem        string           `json:"StarSystem"`
 	StarPos           [3]float64       `json:"StarPos"`
-	SystemAddress     int64            `json:"SystemAddress"`
+	SystemAddress     ID               `json:"SystemAddress"`
 	Name              string           `json:"Name"`
-	MarketID          int64            `json:"MarketID,omitempty"`
-	BodyID            int              `json:"BodyID"`
+	MarketID          ID               `json:"MarketID,omitempty"`
+	BodyID            ID               `json:"BodyID"`
 	BodyName          string           `json:"BodyName"`
 	Latitude          float64          `json:"Latitude"`
 	Longitude         float64          `json:"Longitude"`
@@ -40,6 +40,8 @@ type ApproachSettlementMessage struct {
 	StationFaction    *StationFaction  `json:"StationFaction,omitempty"`
 	StationServices   []string         `json:"StationServices,omitempty"`
 	StationEconomy    *string          `json:"StationEconomy,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type StationEconomy struct {
@@ -57,24 +59,26 @@ type CommodityMessage struct {
 	StationName          string           `json:"stationName"`
 	StationType          string           `json:"stationType,omitempty"`
 	CarrierDockingAccess string           `json:"carrierDockingAccess,omitempty"`
-	MarketID             float64          `json:"marketId"` // Changed to float64
+	MarketID             ID               `json:"marketId"`
 	Horizons             bool             `json:"horizons,omitempty"`
 	Odyssey              bool             `json:"odyssey,omitempty"`
-	Timestamp            string           `json:"timestamp"`
+	Timestamp            Time             `json:"timestamp"`
 	Commodities          []CommodityEntry `json:"commodities"`
 	Economies            []Economy        `json:"economies,omitempty"`
 	Prohibited           []string         `json:"prohibited,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type CommodityEntry struct {
-	Name          string  `json:"name"`
-	MeanPrice     float64 `json:"meanPrice,omitempty"`     // Changed to float64
-	BuyPrice      float64 `json:"buyPrice,omitempty"`      // Changed to float64
-	Stock         float64 `json:"stock,omitempty"`         // Changed to float64
-	StockBracket  float64 `json:"stockBracket,omitempty"`  // Changed to float64
-	SellPrice     float64 `json:"sellPrice,omitempty"`     // Changed to float64
-	Demand        float64 `json:"demand,omitempty"`        // Changed to float64
-	DemandBracket float64 `json:"demandBracket,omitempty"` // Changed to float64
+	Name          string `json:"name"`
+	MeanPrice     Int    `json:"meanPrice"`
+	BuyPrice      Int    `json:"buyPrice"`
+	Stock         Int    `json:"stock"`
+	StockBracket  Int    `json:"stockBracket"`
+	SellPrice     Int    `json:"sellPrice"`
+	Demand        Int    `json:"demand"`
+	DemandBracket Int    `json:"demandBracket"`
 }
 
 type Economy struct {
@@ -84,36 +88,52 @@ type Economy struct {
 
 // Struct for FCMaterials messages
 type FCMaterialsMessage struct {
-	Timestamp string `json:"timestamp"`
+	Timestamp Time   `json:"timestamp"`
 	Event     string `json:"event"`
-	MarketID  int64  `json:"MarketID"`
+	MarketID  ID     `json:"MarketID"`
 	CarrierID string `json:"CarrierID"`
 	Items     Items  `json:"Items"`
+	Horizons  bool   `json:"horizons,omitempty"`
+	Odyssey   bool   `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
+// Items is the CAPI orders.onfootmicroresources object. Either list may be
+// sent as an array or as an object keyed by id; see fcmaterials.go.
 type Items struct {
-	Purchases []Purchase `json:"purchases"`
+	Sales     Sales     `json:"sales"`
+	Purchases Purchases `json:"purchases"`
+}
+
+type Sale struct {
+	ID    ID     `json:"id"`
+	Name  string `json:"name"`
+	Price Int    `json:"price"`
+	Stock Int    `json:"stock"`
 }
 
 type Purchase struct {
 	Name        string `json:"name"`
-	Outstanding int    `json:"outstanding"`
-	Price       int    `json:"price"`
-	Total       int    `json:"total"`
+	Outstanding Int    `json:"outstanding"`
+	Price       Int    `json:"price"`
+	Total       Int    `json:"total"`
 }
 
 type BlackMarketMessage struct {
 	SystemName   string `json:"systemName"`
 	StationName  string `json:"stationName"`
-	MarketID     int64  `json:"marketId,omitempty"` // Renamed from MarketID
-	Timestamp    string `json:"timestamp"`
+	MarketID     ID     `json:"marketId,omitempty"` // Renamed from MarketID
+	Timestamp    Time   `json:"timestamp"`
 	Type         string `json:"name"` // Renamed from Type
-	SellPrice    int    `json:"sellPrice"`
+	SellPrice    Int    `json:"sellPrice"`
 	IllegalGoods bool   `json:"prohibited"` // Renamed from IllegalGoods
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type JournalMessage struct {
-	Timestamp             string     `json:"timestamp"`
+	Timestamp             Time       `json:"timestamp"`
 	Event                 string     `json:"event"`
 	StarSystem            string     `json:"StarSystem"`
 	StarPos               [3]float64 `json:"StarPos"`
@@ -123,13 +143,13 @@ type JournalMessage struct {
 	System                string     `json:"System,omitempty"`
 	WasDiscovered         bool       `json:"WasDiscovered,omitempty"`
 	WasMapped             bool       `json:"WasMapped,omitempty"`
-	SystemAddress         float64    `json:"SystemAddress"`
+	SystemAddress         ID         `json:"SystemAddress"`
 	Horizons              bool       `json:"horizons,omitempty"`
 	Odyssey               bool       `json:"odyssey,omitempty"`
 	Factions              []Faction  `json:"Factions,omitempty"`
-	BodyID                float64    `json:"BodyID,omitempty"`
+	BodyID                ID         `json:"BodyID,omitempty"`
 	ScanType              string     `json:"ScanType,omitempty"`
-	Population            float64    `json:"Population,omitempty"`
+	Population            Int        `json:"Population,omitempty"`
 	PowerplayState        string     `json:"PowerplayState,omitempty"`
 	SystemEconomy         string     `json:"SystemEconomy,omitempty"`
 	SystemSecondEconomy   string     `json:"SystemSecondEconomy,omitempty"`
@@ -138,6 +158,12 @@ type JournalMessage struct {
 	Signals               []Signal   `json:"Signals,omitempty"`
 
 	Taxi bool `json:"taxi,omitempty"`
+
+	// Detail is the full event, e.g. *DockedEvent, when Event is one of the
+	// types in journalEvents and nil otherwise.
+	Detail JournalEvent `json:"-"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Powers struct {
@@ -160,62 +186,70 @@ type State struct {
 }
 
 type FCMaterialsJournalMessage struct {
-	Timestamp   string        `json:"timestamp"`
+	Timestamp   Time          `json:"timestamp"`
 	Event       string        `json:"event"`
-	MarketID    int64         `json:"MarketID"`
+	MarketID    ID            `json:"MarketID"`
 	CarrierName string        `json:"CarrierName"`
 	CarrierID   string        `json:"CarrierID"`
 	Items       []JournalItem `json:"Items"`
 	Horizons    bool          `json:"horizons,omitempty"`
 	Odyssey     bool          `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type JournalItem struct {
-	ID     int    `json:"id"`
+	ID     ID     `json:"id"`
 	Name   string `json:"Name"`
-	Price  int    `json:"Price"`
-	Stock  int    `json:"Stock"`
-	Demand int    `json:"Demand"`
+	Price  Int    `json:"Price"`
+	Stock  Int    `json:"Stock"`
+	Demand Int    `json:"Demand"`
 }
 
 type OutfittingMessage struct {
 	SystemName  string   `json:"systemName"`  // Renamed to "StarSystem" in schema
 	StationName string   `json:"stationName"` // Kept as "StationName"
-	MarketID    int64    `json:"marketId"`    // Renamed to "MarketID"
+	MarketID    ID       `json:"marketId"`    // Renamed to "MarketID"
 	Horizons    bool     `json:"horizons,omitempty"`
 	Odyssey     bool     `json:"odyssey,omitempty"`
-	Timestamp   string   `json:"timestamp"`
+	Timestamp   Time     `json:"timestamp"`
 	Modules     []string `json:"modules"` // Renamed to "Items" in schema
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type NavRouteMessage struct {
-	Timestamp string     `json:"timestamp"`
+	Timestamp Time       `json:"timestamp"`
 	Event     string     `json:"event"`
 	Horizons  bool       `json:"horizons,omitempty"`
 	Odyssey   bool       `json:"odyssey,omitempty"`
 	Route     []NavRoute `json:"Route"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type NavRoute struct {
 	StarSystem    string     `json:"StarSystem"`
-	SystemAddress int64      `json:"SystemAddress"`
+	SystemAddress ID         `json:"SystemAddress"`
 	StarPos       [3]float64 `json:"StarPos"`
 	StarClass     string     `json:"StarClass"`
 }
 
 type FSSSignalDiscoveredMessage struct {
 	Event         string           `json:"event"`
-	Timestamp     string           `json:"timestamp"`
-	SystemAddress int64            `json:"SystemAddress"`
+	Timestamp     Time             `json:"timestamp"`
+	SystemAddress ID               `json:"SystemAddress"`
 	StarSystem    string           `json:"StarSystem"`
 	StarPos       [3]float64       `json:"StarPos"`
 	Horizons      bool             `json:"horizons,omitempty"`
 	Odyssey       bool             `json:"odyssey,omitempty"`
 	Signals       []FSSSignalEvent `json:"signals"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type FSSSignalEvent struct {
-	Timestamp       string `json:"timestamp"`
+	Timestamp       Time   `json:"timestamp"`
 	SignalName      string `json:"SignalName"`
 	SignalType      string `json:"SignalType,omitempty"`
 	IsStation       bool   `json:"IsStation,omitempty"`
@@ -226,23 +260,25 @@ type FSSSignalEvent struct {
 }
 
 type FSSAllBodiesFoundMessage struct {
-	Timestamp     string     `json:"timestamp"`
+	Timestamp     Time       `json:"timestamp"`
 	Event         string     `json:"event"`
 	SystemName    string     `json:"SystemName"`
 	StarPos       [3]float64 `json:"StarPos"`
-	SystemAddress int64      `json:"SystemAddress"`
+	SystemAddress ID         `json:"SystemAddress"`
 	Count         int        `json:"Count"`
 	Horizons      bool       `json:"horizons,omitempty"`
 	Odyssey       bool       `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type ScanBaryCentreMessage struct {
-	Timestamp          string     `json:"timestamp"`
+	Timestamp          Time       `json:"timestamp"`
 	Event              string     `json:"event"`
 	StarSystem         string     `json:"StarSystem"`
 	StarPos            [3]float64 `json:"StarPos"`
-	SystemAddress      int64      `json:"SystemAddress"`
-	BodyID             int        `json:"BodyID"`
+	SystemAddress      ID         `json:"SystemAddress"`
+	BodyID             ID         `json:"BodyID"`
 	SemiMajorAxis      float64    `json:"SemiMajorAxis,omitempty"`
 	Eccentricity       float64    `json:"Eccentricity,omitempty"`
 	OrbitalInclination float64    `json:"OrbitalInclination,omitempty"`
@@ -252,46 +288,54 @@ type ScanBaryCentreMessage struct {
 	MeanAnomaly        float64    `json:"MeanAnomaly,omitempty"`
 	Horizons           bool       `json:"horizons,omitempty"`
 	Odyssey            bool       `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type DockingDeniedMessage struct {
-	Timestamp   string `json:"timestamp"`
+	Timestamp   Time   `json:"timestamp"`
 	Event       string `json:"event"`
-	MarketID    int64  `json:"MarketID"`
+	MarketID    ID     `json:"MarketID"`
 	StationName string `json:"StationName"`
 	StationType string `json:"StationType,omitempty"`
 	Reason      string `json:"Reason"`
 	Horizons    bool   `json:"horizons,omitempty"`
 	Odyssey     bool   `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 type DockingGrantedMessage struct {
-	Timestamp   string `json:"timestamp"`
+	Timestamp   Time   `json:"timestamp"`
 	Event       string `json:"event"`
-	MarketID    int64  `json:"MarketID"`
+	MarketID    ID     `json:"MarketID"`
 	StationName string `json:"StationName"`
 	StationType string `json:"StationType,omitempty"`
 	LandingPad  int    `json:"LandingPad"`
 	Horizons    bool   `json:"horizons,omitempty"`
 	Odyssey     bool   `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 type FSSDiscoveryScanMessage struct {
-	Timestamp     string     `json:"timestamp"`
+	Timestamp     Time       `json:"timestamp"`
 	Event         string     `json:"event"`
 	SystemName    string     `json:"SystemName"`
 	StarPos       [3]float64 `json:"StarPos"`
-	SystemAddress int64      `json:"SystemAddress"`
+	SystemAddress ID         `json:"SystemAddress"`
 	BodyCount     int        `json:"BodyCount"`
 	NonBodyCount  int        `json:"NonBodyCount"`
 	Horizons      bool       `json:"horizons,omitempty"`
 	Odyssey       bool       `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 type CodexEntryMessage struct {
-	Timestamp          string     `json:"timestamp"`
+	Timestamp          Time       `json:"timestamp"`
 	Event              string     `json:"event"`
 	System             string     `json:"System"`
 	StarPos            [3]float64 `json:"StarPos"`
-	SystemAddress      int64      `json:"SystemAddress"`
-	EntryID            int        `json:"EntryID"`
+	SystemAddress      ID         `json:"SystemAddress"`
+	EntryID            ID         `json:"EntryID"`
 	Name               string     `json:"Name"`
 	Region             string     `json:"Region"`
 	Category           string     `json:"Category"`
@@ -299,36 +343,42 @@ type CodexEntryMessage struct {
 	NearestDestination string     `json:"NearestDestination,omitempty"`
 	VoucherAmount      int        `json:"VoucherAmount,omitempty"`
 	Traits             []string   `json:"Traits,omitempty"`
-	BodyID             int        `json:"BodyID,omitempty"`
+	BodyID             ID         `json:"BodyID,omitempty"`
 	BodyName           string     `json:"BodyName,omitempty"`
 	Latitude           float64    `json:"Latitude,omitempty"`
 	Longitude          float64    `json:"Longitude,omitempty"`
 	Horizons           bool       `json:"horizons,omitempty"`
 	Odyssey            bool       `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type ShipyardMessage struct {
 	SystemName     string   `json:"systemName"`  // Renamed to "StarSystem"
 	StationName    string   `json:"stationName"` // Renamed to "StationName"
-	MarketID       int64    `json:"marketId"`    // Renamed to "MarketID"
-	Timestamp      string   `json:"timestamp"`
+	MarketID       ID       `json:"marketId"`    // Renamed to "MarketID"
+	Timestamp      Time     `json:"timestamp"`
 	Horizons       bool     `json:"horizons,omitempty"`
 	Odyssey        bool     `json:"odyssey,omitempty"`
 	AllowCobraMkIV bool     `json:"allowCobraMkIV"`
 	Ships          []string `json:"ships"` // Renamed to "PriceList"
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type FSSBodySignalsMessage struct {
-	Timestamp     string     `json:"timestamp"`
+	Timestamp     Time       `json:"timestamp"`
 	Event         string     `json:"event"`
 	StarSystem    string     `json:"StarSystem"`
 	StarPos       [3]float64 `json:"StarPos"`
-	SystemAddress int64      `json:"SystemAddress"`
-	BodyID        int        `json:"BodyID"`
+	SystemAddress ID         `json:"SystemAddress"`
+	BodyID        ID         `json:"BodyID"`
 	BodyName      string     `json:"BodyName"`
 	Signals       []Signal   `json:"Signals"`
 	Horizons      bool       `json:"horizons,omitempty"`
 	Odyssey       bool       `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type Signal struct {
@@ -337,12 +387,14 @@ type Signal struct {
 }
 
 type NavBeaconScanMessage struct {
-	Timestamp     string     `json:"timestamp"`
+	Timestamp     Time       `json:"timestamp"`
 	Event         string     `json:"event"`
 	StarSystem    string     `json:"StarSystem"`
 	StarPos       [3]float64 `json:"StarPos"`
-	SystemAddress int64      `json:"SystemAddress"`
+	SystemAddress ID         `json:"SystemAddress"`
 	NumBodies     int        `json:"NumBodies"`
 	Horizons      bool       `json:"horizons,omitempty"`
 	Odyssey       bool       `json:"odyssey,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
@@ -0,0 +1,95 @@
+package eddn
+
+import (
+	"context"
+	"log"
+)
+
+const DefaultRelay = "tcp://eddn.edcd.io:9500"
+
+// Client subscribes to an EDDN relay and yields decoded envelopes.
+type Client struct {
+	Subscriber *Subscriber
+
+	// OnFrame, if set, sees every admitted raw frame, in arrival order, on
+	// a goroutine of its own. It has a queue of QueueSize frames that
+	// Overflow applies to like the decode queue, so a slow hook either
+	// pushes back on the relay or has frames dropped; dropped frames are
+	// not decoded either.
+	OnFrame func(frame []byte)
+
+	// OnError is called for every frame that fails to decode. When nil the
+	// error is logged. Decode workers call it concurrently.
+	OnError func(err error)
+
+	// Workers is the number of decode goroutines; 0 means one per CPU.
+	Workers int
+	// QueueSize bounds how many frames may wait for a worker; 0 means 1024.
+	QueueSize int
+	// Overflow decides what happens to a frame when the queue is full.
+	Overflow Overflow
+	// Ordered makes each schema's envelopes leave in the order their
+	// frames arrived. Schemas are sequenced independently, so a slow frame
+	// only holds back later frames with the same ordering key.
+	Ordered bool
+	// OrderBy returns the ordering key of a frame when Ordered is set. By
+	// default it is the $schemaRef, found with PeekSchemaRef; frames
+	// without one share the "" sequence. OneSequence orders all frames.
+	OrderBy func(frame []byte) string
+	// OnDrop, if set, is called for every frame discarded under DropNewest.
+	OnDrop func(frame []byte)
+
+	// Tests, if set, receives messages for /test schemas, which are
+	// otherwise discarded so development traffic stays out of the main
+	// stream. Consume closes it along with out.
+	Tests chan<- *Envelope
+
+	// Validator, if set, checks every decoded message. Failures are
+	// reported through OnError as a DecodeError with Stage ErrValidation.
+	Validator Validator
+	// Strict drops messages that fail validation. Otherwise they are
+	// delivered with Envelope.Invalid set.
+	Strict bool
+
+	// Dedup, if set, drops messages it has already seen.
+	Dedup *Deduper
+
+	dropped uint64
+}
+
+// Validator checks a decoded envelope against its schema. Validate may be
+// called from several decode workers at once.
+type Validator interface {
+	Validate(env *Envelope) error
+}
+
+func NewClient(endpoint string) *Client {
+	return &Client{Subscriber: NewSubscriber(endpoint)}
+}
+
+// Run delivers every decodable message from the relay to out until ctx is
+// cancelled. out is closed when Run returns.
+func (c *Client) Run(ctx context.Context, out chan<- *Envelope) error {
+	frames := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Subscriber.Run(ctx, frames)
+		close(frames)
+	}()
+
+	c.Consume(ctx, frames, out)
+	return <-errc
+}
+
+func (c *Client) error(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+		return
+	}
+	LogError(err)
+}
+
+// LogError is the default OnError behaviour.
+func LogError(err error) {
+	log.Printf("%v\n", err)
+}
@@ -0,0 +1,207 @@
+package eddn
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Envelope is a fully decoded EDDN message. Message holds a pointer to the
+// schema specific struct, e.g. *CommodityMessage, and Schema is SchemaRef
+// broken into its parts. Raw is the message object
+// and Data the whole document it came in. Received is when the Client
+// decoded the frame and is zero for envelopes built by Decode alone.
+type Envelope struct {
+	SchemaRef string
+	Schema    Schema
+	Header    EDDNHeader
+	Message   interface{}
+	Raw       json.RawMessage
+	Data      json.RawMessage
+	Received  time.Time
+	// Unknown lists the message fields its struct does not declare.
+	Unknown []string
+	// Invalid holds the validation failure for messages that failed the
+	// Client's Validator but were passed on because Strict was off.
+	Invalid error
+}
+
+// Stages of the decode pipeline a DecodeError can come from.
+var (
+	ErrDecompress    = errors.New("decompressing message")
+	ErrInvalidJSON   = errors.New("invalid JSON")
+	ErrEnvelope      = errors.New("parsing EDDN JSON")
+	ErrUnknownSchema = errors.New("unknown schema")
+	ErrMessage       = errors.New("parsing specific message")
+	ErrValidation    = errors.New("validating message")
+)
+
+// DecodeError reports which stage of the pipeline rejected a frame.
+// errors.Is matches it against the Err* stage values.
+type DecodeError struct {
+	Stage     error
+	SchemaRef string
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	msg := e.Stage.Error()
+	if e.SchemaRef != "" {
+		msg += " " + e.SchemaRef
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *DecodeError) Unwrap() error { return e.Stage }
+
+// Decode turns a raw zlib frame from the relay into an Envelope. Frames that
+// are already plain JSON, as found in decompressed captures, skip the zlib step.
+func Decode(frame []byte) (*Envelope, error) {
+	data, err := Unpack(frame)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeJSON(data)
+}
+
+// Unpack returns the JSON document in a raw frame, decompressing it unless
+// it is already plain JSON.
+func Unpack(frame []byte) ([]byte, error) {
+	if len(frame) > 0 && frame[0] == '{' {
+		return frame, nil
+	}
+	data, err := decompressZlib(frame)
+	if err != nil {
+		return nil, &DecodeError{Stage: ErrDecompress, Err: err}
+	}
+	return data, nil
+}
+
+// PeekHeader decodes only the envelope header of a raw frame, for callers
+// that need the gateway timestamp without paying for the full message.
+// Decompression stops once the header has been read.
+func PeekHeader(frame []byte) (EDDNHeader, error) {
+	var h EDDNHeader
+	err := peekMembers(frame, func(key string, dec *json.Decoder) (bool, error) {
+		if key != "header" {
+			return true, skipValue(dec)
+		}
+		return false, dec.Decode(&h)
+	})
+	return h, err
+}
+
+// PeekSchemaRef returns the $schemaRef of a raw frame, inflating only as
+// much of it as needed to find it. It returns "" if the frame cannot be
+// read or has no $schemaRef.
+func PeekSchemaRef(frame []byte) string {
+	var ref string
+	peekMembers(frame, func(key string, dec *json.Decoder) (bool, error) {
+		if key != "$schemaRef" {
+			return true, skipValue(dec)
+		}
+		return false, dec.Decode(&ref)
+	})
+	return ref
+}
+
+// peekMembers streams the top level of a frame, calling fn with each member
+// name until it returns false. fn must consume the member's value from dec.
+// Decompression stops where fn does, so members that come early, as
+// $schemaRef and header usually do, are found without reading the message.
+func peekMembers(frame []byte, fn func(key string, dec *json.Decoder) (bool, error)) error {
+	var r io.Reader = bytes.NewReader(frame)
+	if len(frame) == 0 || frame[0] != '{' {
+		zr, err := zlib.NewReader(bytes.NewReader(frame))
+		if err != nil {
+			return &DecodeError{Stage: ErrDecompress, Err: err}
+		}
+		defer zr.Close()
+		r = zr
+	}
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return &DecodeError{Stage: ErrInvalidJSON, Err: fmt.Errorf("frame is not a JSON object")}
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return &DecodeError{Stage: ErrInvalidJSON, Err: err}
+		}
+		key, _ := tok.(string)
+		more, err := fn(key, dec)
+		if err != nil {
+			return &DecodeError{Stage: ErrEnvelope, Err: err}
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+func skipValue(dec *json.Decoder) error {
+	var skip json.RawMessage
+	return dec.Decode(&skip)
+}
+
+// DecodeJSON decodes an already decompressed EDDN message.
+func DecodeJSON(data []byte) (*Envelope, error) {
+	if !json.Valid(data) {
+		return nil, &DecodeError{Stage: ErrInvalidJSON, Err: fmt.Errorf("%.200s", data)}
+	}
+
+	var eddnMsg EDDN
+	if err := json.Unmarshal(data, &eddnMsg); err != nil {
+		return nil, &DecodeError{Stage: ErrEnvelope, Err: err}
+	}
+
+	schema, ok := ParseSchemaRef(eddnMsg.SchemaRef)
+	var newMsg func() interface{}
+	if ok {
+		newMsg = lookup(schema)
+	}
+	if newMsg == nil {
+		return nil, &DecodeError{Stage: ErrUnknownSchema, SchemaRef: eddnMsg.SchemaRef}
+	}
+
+	specificMsg := newMsg()
+	if err := json.Unmarshal(eddnMsg.Message, specificMsg); err != nil {
+		return nil, &DecodeError{Stage: ErrMessage, SchemaRef: eddnMsg.SchemaRef, Err: err}
+	}
+
+	unknown := unknownFields(eddnMsg.Message, specificMsg)
+
+	return &Envelope{
+		SchemaRef: eddnMsg.SchemaRef,
+		Schema:    schema,
+		Header:    eddnMsg.Header,
+		Message:   specificMsg,
+		Raw:       eddnMsg.Message,
+		Data:      data,
+		Unknown:   unknown,
+	}, nil
+}
+
+// decompressZlib decompresses the zlib-compressed message.
+func decompressZlib(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressedData, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return decompressedData, nil
+}
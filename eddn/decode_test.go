@@ -0,0 +1,192 @@
+package eddn
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+const commodityFrame = `{
+	"$schemaRef": "https://eddn.edcd.io/schemas/commodity/3",
+	"header": {"uploaderID": "test", "softwareName": "test", "softwareVersion": "1",
+		"gatewayTimestamp": "2024-05-01T10:00:00Z"},
+	"message": {"systemName": "Sol", "stationName": "Abraham Lincoln", "marketId": 128016640,
+		"timestamp": "2024-05-01T09:59:58Z",
+		"commodities": [{"name": "gold", "meanPrice": 3, "buyPrice": 1, "stock": 0, "stockBracket": 0,
+			"sellPrice": 2, "demand": 0, "demandBracket": 0}]}
+}`
+
+func pack(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	for _, frame := range [][]byte{pack(t, commodityFrame), []byte(commodityFrame)} {
+		env, err := Decode(frame)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		msg, ok := env.Message.(*CommodityMessage)
+		if !ok {
+			t.Fatalf("Message is %T, want *CommodityMessage", env.Message)
+		}
+		if msg.StationName != "Abraham Lincoln" || len(msg.Commodities) != 1 {
+			t.Errorf("decoded %+v", msg)
+		}
+		if env.Schema.Family != "commodity" || env.Header.GatewayTimestamp.IsZero() {
+			t.Errorf("envelope schema %+v, header %+v", env.Schema, env.Header)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	corrupt := pack(t, commodityFrame)
+	corrupt = append(corrupt[:len(corrupt)/2:len(corrupt)/2], 0xff, 0xff, 0xff)
+
+	tests := []struct {
+		name  string
+		frame []byte
+		stage error
+	}{
+		{"corrupt zlib", corrupt, ErrDecompress},
+		{"not zlib", []byte("\x00\x01\x02"), ErrDecompress},
+		{"invalid JSON", pack(t, `{"$schemaRef": `), ErrInvalidJSON},
+		{"bad envelope", pack(t, `{"$schemaRef": 3}`), ErrEnvelope},
+		{"unknown schema", pack(t, `{"$schemaRef": "https://eddn.edcd.io/schemas/nosuch/1", "message": {}}`), ErrUnknownSchema},
+		{"bad message", pack(t, `{"$schemaRef": "https://eddn.edcd.io/schemas/commodity/3", "message": {"marketId": "x"}}`), ErrMessage},
+	}
+	for _, tt := range tests {
+		_, err := Decode(tt.frame)
+		if !errors.Is(err, tt.stage) {
+			t.Errorf("%s: error %v, want stage %v", tt.name, err, tt.stage)
+			continue
+		}
+		var de *DecodeError
+		if !errors.As(err, &de) || de.Stage != tt.stage {
+			t.Errorf("%s: error %#v, want a DecodeError with stage %v", tt.name, err, tt.stage)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	for _, frame := range [][]byte{pack(t, commodityFrame), []byte(commodityFrame)} {
+		if ref := PeekSchemaRef(frame); ref != "https://eddn.edcd.io/schemas/commodity/3" {
+			t.Errorf("PeekSchemaRef = %q", ref)
+		}
+		h, err := PeekHeader(frame)
+		if err != nil || !h.GatewayTimestamp.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("PeekHeader = %+v, %v", h, err)
+		}
+	}
+	if ref := PeekSchemaRef([]byte("\x00\x01")); ref != "" {
+		t.Errorf("PeekSchemaRef of garbage = %q", ref)
+	}
+}
+
+// numbered returns a plain JSON frame of schema whose station is named n.
+func numbered(schema string, n int) []byte {
+	return []byte(fmt.Sprintf(`{"$schemaRef": "https://eddn.edcd.io/schemas/%s",
+		"header": {"uploaderID": "test", "softwareName": "test", "softwareVersion": "1"},
+		"message": {"systemName": "Sol", "StarSystem": "Sol", "event": "Docked", "stationName": "%d",
+			"StationName": "%d", "marketId": 1, "timestamp": "2024-05-01T10:00:00Z"}}`, schema, n, n))
+}
+
+func stationOf(env *Envelope) string {
+	switch m := env.Message.(type) {
+	case *CommodityMessage:
+		return m.StationName
+	case *JournalMessage:
+		if d, ok := m.Detail.(*DockedEvent); ok {
+			return d.StationName
+		}
+	}
+	return ""
+}
+
+func TestConsumeOrdered(t *testing.T) {
+	const n = 200
+	in := make(chan []byte)
+	out := make(chan *Envelope)
+	var mu sync.Mutex
+	var errs []error
+	c := &Client{Workers: 4, QueueSize: 8, Ordered: true, OnError: func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}}
+	var framed int
+	c.OnFrame = func([]byte) { framed++ }
+
+	go func() {
+		for i := 0; i < n; i++ {
+			in <- numbered("commodity/3", i)
+			in <- numbered("journal/1", i)
+		}
+		in <- []byte("garbage")
+		close(in)
+	}()
+	go c.Consume(context.Background(), in, out)
+
+	next := map[string]int{}
+	total := 0
+	for env := range out {
+		want := fmt.Sprint(next[env.Schema.Family])
+		if got := stationOf(env); got != want {
+			t.Fatalf("%s message %s arrived, want %s", env.Schema.Family, got, want)
+		}
+		next[env.Schema.Family]++
+		total++
+	}
+	if total != 2*n {
+		t.Errorf("delivered %d envelopes, want %d", total, 2*n)
+	}
+	// out is closed only after the hooks have finished.
+	if framed != 2*n+1 {
+		t.Errorf("OnFrame saw %d frames, want %d", framed, 2*n+1)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrDecompress) {
+		t.Errorf("errors %v, want one decompression error", errs)
+	}
+}
+
+func TestConsumeDropNewest(t *testing.T) {
+	in := make(chan []byte)
+	out := make(chan *Envelope)
+	dropped := 0
+	c := &Client{Workers: 1, QueueSize: 1, Overflow: DropNewest, OnDrop: func([]byte) { dropped++ }}
+
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 50; i++ {
+			in <- numbered("commodity/3", i)
+		}
+		close(in)
+		close(sent)
+	}()
+	go c.Consume(context.Background(), in, out)
+
+	// Nobody reads out until every frame is sent, so most of them must be
+	// dropped rather than block the sender.
+	<-sent
+	delivered := 0
+	for range out {
+		delivered++
+	}
+	if uint64(dropped) != c.Dropped() || delivered+dropped != 50 || dropped == 0 {
+		t.Errorf("delivered %d, dropped %d (Dropped() = %d) of 50", delivered, dropped, c.Dropped())
+	}
+}
@@ -0,0 +1,135 @@
+package eddn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Deduper suppresses messages already seen within a time window. Messages
+// are keyed on their $schemaRef and a canonical form of the message, so the
+// same event relayed by several uploaders, or re-sent, matches regardless
+// of key order or whitespace; the header is not part of the key.
+type Deduper struct {
+	// Window is how long a message is remembered.
+	Window time.Duration
+	// MaxEntries bounds memory; the oldest entries are forgotten first.
+	MaxEntries int
+	// OnDuplicate, if set, is called for every suppressed message.
+	OnDuplicate func(env *Envelope)
+
+	mu         sync.Mutex
+	seen       map[[16]byte]time.Time
+	order      []dedupEntry
+	head       int // entries before head in order have been forgotten
+	bySchema   map[string]uint64
+	bySoftware map[string]uint64
+
+	now func() time.Time // replaced in tests
+}
+
+type dedupEntry struct {
+	key  [16]byte
+	seen time.Time
+}
+
+func NewDeduper(window time.Duration, maxEntries int) *Deduper {
+	return &Deduper{
+		Window:     window,
+		MaxEntries: maxEntries,
+		seen:       make(map[[16]byte]time.Time),
+		bySchema:   make(map[string]uint64),
+		bySoftware: make(map[string]uint64),
+		now:        time.Now,
+	}
+}
+
+// Duplicate reports whether env repeats a message seen within the window,
+// and remembers it otherwise. It is safe for concurrent use.
+func (d *Deduper) Duplicate(env *Envelope) bool {
+	key, ok := dedupKey(env)
+	if !ok {
+		return false
+	}
+	d.mu.Lock()
+	now := d.now()
+	d.expire(now, false)
+	if first, dup := d.seen[key]; dup && now.Sub(first) <= d.Window {
+		d.bySchema[env.SchemaRef]++
+		d.bySoftware[env.Header.SoftwareName]++
+		d.mu.Unlock()
+		if d.OnDuplicate != nil {
+			d.OnDuplicate(env)
+		}
+		return true
+	}
+	d.expire(now, true)
+	d.seen[key] = now
+	d.order = append(d.order, dedupEntry{key, now})
+	d.mu.Unlock()
+	return false
+}
+
+// expire forgets entries older than the window and, if room is set, the
+// oldest entries until there is room for one more.
+func (d *Deduper) expire(now time.Time, room bool) {
+	for d.head < len(d.order) {
+		e := d.order[d.head]
+		if now.Sub(e.seen) <= d.Window && (!room || d.MaxEntries <= 0 || len(d.seen) < d.MaxEntries) {
+			break
+		}
+		if d.seen[e.key].Equal(e.seen) {
+			delete(d.seen, e.key)
+		}
+		d.head++
+	}
+	// Compact once most of order is forgotten, so the copy is paid for by
+	// the entries expired since the last one.
+	if d.head > len(d.order)/2 {
+		d.order = append(d.order[:0], d.order[d.head:]...)
+		d.head = 0
+	}
+}
+
+// DedupStats counts suppressed messages.
+type DedupStats struct {
+	BySchema   map[string]uint64 `json:"bySchema"`
+	BySoftware map[string]uint64 `json:"bySoftware"`
+}
+
+// Stats returns the duplicate counts so far.
+func (d *Deduper) Stats() DedupStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := DedupStats{BySchema: make(map[string]uint64), BySoftware: make(map[string]uint64)}
+	for k, v := range d.bySchema {
+		st.BySchema[k] = v
+	}
+	for k, v := range d.bySoftware {
+		st.BySoftware[k] = v
+	}
+	return st
+}
+
+// dedupKey hashes the schema and the message re-encoded with sorted keys.
+func dedupKey(env *Envelope) ([16]byte, bool) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(env.Raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return [16]byte{}, false
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return [16]byte{}, false
+	}
+	h := sha256.New()
+	h.Write([]byte(env.SchemaRef))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	var key [16]byte
+	copy(key[:], h.Sum(nil))
+	return key, true
+}
@@ -0,0 +1,121 @@
+package eddn
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// clock is a fake time source for Deduper.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestDeduper(window time.Duration, max int) (*Deduper, *clock) {
+	c := &clock{t: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	d := NewDeduper(window, max)
+	d.now = c.now
+	return d, c
+}
+
+func dedupEnv(schemaRef, software, raw string) *Envelope {
+	return &Envelope{SchemaRef: schemaRef, Header: EDDNHeader{SoftwareName: software}, Raw: []byte(raw)}
+}
+
+const journalRef = "https://eddn.edcd.io/schemas/journal/1"
+
+func TestDedupKey(t *testing.T) {
+	first := dedupEnv(journalRef, "EDMC", `{"event": "Docked", "StarPos": [1, 2.5, 3], "nested": {"a": 1, "b": 2}}`)
+	tests := []struct {
+		name string
+		env  *Envelope
+		dup  bool
+	}{
+		{"identical", first, true},
+		{"key order and whitespace", dedupEnv(journalRef, "EDMC", `{"nested":{"b":2,"a":1},"StarPos":[1,2.5,3],"event":"Docked"}`), true},
+		{"other software", dedupEnv(journalRef, "EDDiscovery", `{"event": "Docked", "StarPos": [1, 2.5, 3], "nested": {"a": 1, "b": 2}}`), true},
+		{"other value", dedupEnv(journalRef, "EDMC", `{"event": "Docked", "StarPos": [1, 2.5, 4], "nested": {"a": 1, "b": 2}}`), false},
+		{"array order", dedupEnv(journalRef, "EDMC", `{"event": "Docked", "StarPos": [3, 2.5, 1], "nested": {"a": 1, "b": 2}}`), false},
+		{"other schema", dedupEnv("https://eddn.edcd.io/schemas/journal/1/test", "EDMC", `{"event": "Docked", "StarPos": [1, 2.5, 3], "nested": {"a": 1, "b": 2}}`), false},
+	}
+	for _, tt := range tests {
+		d, _ := newTestDeduper(time.Minute, 0)
+		d.Duplicate(first)
+		if got := d.Duplicate(tt.env); got != tt.dup {
+			t.Errorf("%s: Duplicate = %v, want %v", tt.name, got, tt.dup)
+		}
+	}
+
+	d, _ := newTestDeduper(time.Minute, 0)
+	bad := dedupEnv(journalRef, "EDMC", `{"event": `)
+	if d.Duplicate(bad) || d.Duplicate(bad) {
+		t.Error("undecodable messages are never duplicates")
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	d, c := newTestDeduper(time.Minute, 0)
+	env := dedupEnv(journalRef, "EDMC", `{"event": "Scan"}`)
+
+	steps := []struct {
+		after time.Duration
+		dup   bool
+	}{
+		{0, false},
+		{30 * time.Second, true},
+		// The window runs from the first sighting; duplicates do not extend it.
+		{30 * time.Second, true},
+		{time.Nanosecond, false},
+		{59 * time.Second, true},
+	}
+	for i, s := range steps {
+		c.t = c.t.Add(s.after)
+		if got := d.Duplicate(env); got != s.dup {
+			t.Errorf("step %d: Duplicate = %v, want %v", i, got, s.dup)
+		}
+	}
+
+	st := d.Stats()
+	if st.BySchema[journalRef] != 3 || st.BySoftware["EDMC"] != 3 {
+		t.Errorf("Stats = %+v, want 3 duplicates", st)
+	}
+}
+
+func TestDedupMaxEntries(t *testing.T) {
+	d, c := newTestDeduper(time.Hour, 2)
+	msg := func(i int) *Envelope { return dedupEnv(journalRef, "EDMC", fmt.Sprintf(`{"n": %d}`, i)) }
+
+	for i := 0; i < 3; i++ {
+		c.t = c.t.Add(time.Second)
+		if d.Duplicate(msg(i)) {
+			t.Fatalf("message %d reported as duplicate", i)
+		}
+	}
+	// Remembering the third forgot the first, the oldest.
+	if d.Duplicate(msg(0)) {
+		t.Error("oldest entry was not evicted")
+	}
+	// Remembering the first again evicted the second.
+	if d.Duplicate(msg(1)) {
+		t.Error("second entry was not evicted")
+	}
+	if !d.Duplicate(msg(0)) {
+		t.Error("newest entry was evicted")
+	}
+	if len(d.seen) > 2 {
+		t.Errorf("%d entries remembered, want at most 2", len(d.seen))
+	}
+}
+
+func TestDedupCompacts(t *testing.T) {
+	d, c := newTestDeduper(time.Second, 0)
+	for i := 0; i < 10000; i++ {
+		c.t = c.t.Add(100 * time.Millisecond)
+		d.Duplicate(dedupEnv(journalRef, "EDMC", fmt.Sprintf(`{"n": %d}`, i)))
+	}
+	// About ten entries are live at a time; what has expired must not pile
+	// up in order.
+	if len(d.seen) > 11 || len(d.order)-d.head != len(d.seen) || len(d.order) > 2*len(d.seen)+1 {
+		t.Errorf("%d live entries, order holds %d from %d", len(d.seen), len(d.order), d.head)
+	}
+}
@@ -0,0 +1,83 @@
+package eddn
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Dispatcher routes decoded envelopes to the handlers registered for their
+// message type. Handlers for the same type run in registration order.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	handlers map[reflect.Type][]func(*Envelope)
+	catchAll []func(*Envelope)
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[reflect.Type][]func(*Envelope))}
+}
+
+// DefaultDispatcher is the dispatcher used by the package level On, so other
+// packages can hook in from an init function.
+var DefaultDispatcher = NewDispatcher()
+
+// TestDispatcher is where callers that opt in to /test schema messages
+// conventionally serve Client.Tests, keeping them apart from DefaultDispatcher.
+var TestDispatcher = NewDispatcher()
+
+// On registers fn on DefaultDispatcher for messages of type T,
+// e.g. On(func(h EDDNHeader, msg *CommodityMessage) { ... }).
+func On[T any](fn func(header EDDNHeader, msg *T)) {
+	Handle(DefaultDispatcher, fn)
+}
+
+// Handle registers fn on d for messages of type T.
+func Handle[T any](d *Dispatcher, fn func(header EDDNHeader, msg *T)) {
+	t := reflect.TypeOf((*T)(nil))
+	d.add(t, func(env *Envelope) {
+		fn(env.Header, env.Message.(*T))
+	})
+}
+
+// OnAny registers fn on DefaultDispatcher for every message regardless of
+// type. These handlers run before the typed ones.
+func OnAny(fn func(env *Envelope)) {
+	DefaultDispatcher.HandleAny(fn)
+}
+
+// HandleAny registers fn on d for every message regardless of type.
+func (d *Dispatcher) HandleAny(fn func(env *Envelope)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.catchAll = append(d.catchAll, fn)
+}
+
+func (d *Dispatcher) add(t reflect.Type, h func(*Envelope)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[t] = append(d.handlers[t], h)
+}
+
+// Dispatch runs every catch-all handler and then every handler registered
+// for env's message type, and reports how many ran.
+func (d *Dispatcher) Dispatch(env *Envelope) int {
+	d.mu.RLock()
+	catchAll := d.catchAll
+	hs := d.handlers[reflect.TypeOf(env.Message)]
+	d.mu.RUnlock()
+
+	for _, h := range catchAll {
+		h(env)
+	}
+	for _, h := range hs {
+		h(env)
+	}
+	return len(catchAll) + len(hs)
+}
+
+// Serve dispatches envelopes from in until it is closed.
+func (d *Dispatcher) Serve(in <-chan *Envelope) {
+	for env := range in {
+		d.Dispatch(env)
+	}
+}
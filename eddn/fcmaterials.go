@@ -0,0 +1,161 @@
+package eddn
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// Sales decodes the CAPI sales list. The API serialises an empty or
+// sequentially keyed list as an array and anything else as an object keyed
+// by id; both decode to a slice ordered by id. A sale missing its id takes
+// it from the key. A missing or null list stays nil.
+type Sales []Sale
+
+func (s *Sales) UnmarshalJSON(data []byte) error {
+	keys, values, err := arrayOrObject(data)
+	if err != nil || values == nil {
+		*s = nil
+		return err
+	}
+	out := make(Sales, len(values))
+	for i, v := range values {
+		if err := json.Unmarshal(v, &out[i]); err != nil {
+			return err
+		}
+		if out[i].ID == 0 && keys != nil {
+			id, _ := strconv.ParseInt(keys[i], 10, 64)
+			out[i].ID = ID(id)
+		}
+	}
+	*s = out
+	return nil
+}
+
+// Purchases decodes the CAPI purchases list, which can be shaped like
+// Sales.
+type Purchases []Purchase
+
+func (p *Purchases) UnmarshalJSON(data []byte) error {
+	_, values, err := arrayOrObject(data)
+	if err != nil || values == nil {
+		*p = nil
+		return err
+	}
+	out := make(Purchases, len(values))
+	for i, v := range values {
+		if err := json.Unmarshal(v, &out[i]); err != nil {
+			return err
+		}
+	}
+	*p = out
+	return nil
+}
+
+// arrayOrObject splits a JSON array, or an object whose members are list
+// entries, into its values. For objects the keys are returned too, and the
+// values are ordered by key, numerically where the keys are numbers.
+func arrayOrObject(data []byte) ([]string, []json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil, nil
+	}
+	if data[0] == '[' {
+		var values []json.RawMessage
+		err := json.Unmarshal(data, &values)
+		return nil, values, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, nil, err
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.ParseInt(keys[i], 10, 64)
+		b, errB := strconv.ParseInt(keys[j], 10, 64)
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	values := make([]json.RawMessage, len(keys))
+	for i, k := range keys {
+		values[i] = obj[k]
+	}
+	return keys, values, nil
+}
+
+// CarrierMaterial is one bartender order: a micro-resource the carrier
+// sells, with the units in stock, or buys, with the units still wanted.
+type CarrierMaterial struct {
+	ID    ID     `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Price Int    `json:"price"`
+	Count Int    `json:"count"`
+}
+
+// CarrierMaterialsSnapshot is a carrier's bartender as reported by either
+// fcmaterials schema. A nil list is one the source did not report, as
+// opposed to an empty one.
+type CarrierMaterialsSnapshot struct {
+	Timestamp   Time              `json:"timestamp"`
+	MarketID    ID                `json:"marketId"`
+	CarrierID   string            `json:"carrierId"`
+	CarrierName string            `json:"carrierName,omitempty"`
+	Sales       []CarrierMaterial `json:"sales"`
+	Purchases   []CarrierMaterial `json:"purchases"`
+}
+
+// CarrierMaterials is implemented by both fcmaterials message types.
+type CarrierMaterials interface {
+	Materials() CarrierMaterialsSnapshot
+}
+
+// Materials lists the CAPI orders. The API does not send the carrier name.
+func (m *FCMaterialsMessage) Materials() CarrierMaterialsSnapshot {
+	s := CarrierMaterialsSnapshot{
+		Timestamp: m.Timestamp,
+		MarketID:  m.MarketID,
+		CarrierID: m.CarrierID,
+	}
+	if m.Items.Sales != nil {
+		s.Sales = []CarrierMaterial{}
+	}
+	if m.Items.Purchases != nil {
+		s.Purchases = []CarrierMaterial{}
+	}
+	for _, sale := range m.Items.Sales {
+		s.Sales = append(s.Sales, CarrierMaterial{ID: sale.ID, Name: sale.Name, Price: sale.Price, Count: sale.Stock})
+	}
+	for _, p := range m.Items.Purchases {
+		s.Purchases = append(s.Purchases, CarrierMaterial{Name: p.Name, Price: p.Price, Count: p.Outstanding})
+	}
+	return s
+}
+
+// Materials lists the journal orders. The journal has one entry per
+// material with both Stock and Demand, and the carrier sells at Price when
+// it has stock and buys at Price when it has demand.
+func (m *FCMaterialsJournalMessage) Materials() CarrierMaterialsSnapshot {
+	s := CarrierMaterialsSnapshot{
+		Timestamp:   m.Timestamp,
+		MarketID:    m.MarketID,
+		CarrierID:   m.CarrierID,
+		CarrierName: m.CarrierName,
+		Sales:       []CarrierMaterial{},
+		Purchases:   []CarrierMaterial{},
+	}
+	for _, item := range m.Items {
+		if item.Stock > 0 {
+			s.Sales = append(s.Sales, CarrierMaterial{ID: item.ID, Name: item.Name, Price: item.Price, Count: item.Stock})
+		}
+		if item.Demand > 0 {
+			s.Purchases = append(s.Purchases, CarrierMaterial{ID: item.ID, Name: item.Name, Price: item.Price, Count: item.Demand})
+		}
+	}
+	return s
+}
@@ -0,0 +1,318 @@
+package eddn
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JournalEvent is implemented by the typed events a JournalMessage decodes
+// into. Handlers type-switch on JournalMessage.Detail:
+//
+//	switch ev := msg.Detail.(type) {
+//	case *DockedEvent:
+//		...
+//	case *FSDJumpEvent:
+//		...
+//	}
+type JournalEvent interface {
+	Journal() *JournalBase
+}
+
+// JournalBase holds the fields the journal/1 schema requires of every event.
+type JournalBase struct {
+	Timestamp     Time       `json:"timestamp"`
+	Event         string     `json:"event"`
+	StarSystem    string     `json:"StarSystem"`
+	StarPos       [3]float64 `json:"StarPos"`
+	SystemAddress ID         `json:"SystemAddress"`
+	Horizons      bool       `json:"horizons,omitempty"`
+	Odyssey       bool       `json:"odyssey,omitempty"`
+}
+
+func (b *JournalBase) Journal() *JournalBase { return b }
+
+// journalEvents maps the event field to the type it decodes into.
+var journalEvents = map[string]func() JournalEvent{
+	"Docked":          func() JournalEvent { return &DockedEvent{} },
+	"FSDJump":         func() JournalEvent { return &FSDJumpEvent{} },
+	"Scan":            func() JournalEvent { return &ScanEvent{} },
+	"Location":        func() JournalEvent { return &LocationEvent{} },
+	"SAASignalsFound": func() JournalEvent { return &SAASignalsFoundEvent{} },
+	"CarrierJump":     func() JournalEvent { return &CarrierJumpEvent{} },
+	"CodexEntry":      func() JournalEvent { return &CodexEntryEvent{} },
+}
+
+// NewJournalEvent returns a new typed event for the journal event name, or
+// nil if there is no dedicated type for it.
+func NewJournalEvent(event string) JournalEvent {
+	if newEvent, ok := journalEvents[event]; ok {
+		return newEvent()
+	}
+	return nil
+}
+
+// UnmarshalJSON fills the flat JournalMessage fields as before and decodes
+// the full event into Detail when its type is known.
+func (m *JournalMessage) UnmarshalJSON(data []byte) error {
+	type plain JournalMessage
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+	newEvent, ok := journalEvents[m.Event]
+	if !ok {
+		m.Detail = nil
+		return nil
+	}
+	ev := newEvent()
+	if err := json.Unmarshal(data, ev); err != nil {
+		return fmt.Errorf("decoding %s event: %w", m.Event, err)
+	}
+	m.Detail = ev
+	return nil
+}
+
+// MarshalJSON writes the flat fields overlaid with those of Detail, so an
+// event's own fields survive a decode and re-encode.
+func (m JournalMessage) MarshalJSON() ([]byte, error) {
+	type plain JournalMessage
+	flat, err := json.Marshal(plain(m))
+	if err != nil || m.Detail == nil {
+		return flat, err
+	}
+	detail, err := json.Marshal(m.Detail)
+	if err != nil {
+		return nil, err
+	}
+	var fields, extra map[string]json.RawMessage
+	if err := json.Unmarshal(flat, &fields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(detail, &extra); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		fields[k] = v
+	}
+	return json.Marshal(fields)
+}
+
+// declared compares journal messages against their typed event, which
+// covers far more fields than the flat struct.
+func (m *JournalMessage) declared() interface{} {
+	if m.Detail != nil {
+		return m.Detail
+	}
+	return m
+}
+
+// SystemInfo is the system state reported on arrival by FSDJump, Location
+// and CarrierJump.
+type SystemInfo struct {
+	SystemAllegiance    string `json:"SystemAllegiance,omitempty"`
+	SystemEconomy       string `json:"SystemEconomy,omitempty"`
+	SystemSecondEconomy string `json:"SystemSecondEconomy,omitempty"`
+	SystemGovernment    string `json:"SystemGovernment,omitempty"`
+	SystemSecurity      string `json:"SystemSecurity,omitempty"`
+	Population          Int    `json:"Population,omitempty"`
+
+	Body     string `json:"Body,omitempty"`
+	BodyID   ID     `json:"BodyID,omitempty"`
+	BodyType string `json:"BodyType,omitempty"`
+
+	Powers                        []string                    `json:"Powers,omitempty"`
+	ControllingPower              string                      `json:"ControllingPower,omitempty"`
+	PowerplayState                string                      `json:"PowerplayState,omitempty"`
+	PowerplayStateControlProgress float64                     `json:"PowerplayStateControlProgress,omitempty"`
+	PowerplayStateReinforcement   int64                       `json:"PowerplayStateReinforcement,omitempty"`
+	PowerplayStateUndermining     int64                       `json:"PowerplayStateUndermining,omitempty"`
+	PowerplayConflictProgress     []PowerplayConflictProgress `json:"PowerplayConflictProgress,omitempty"`
+
+	Factions      []Faction       `json:"Factions,omitempty"`
+	SystemFaction *StationFaction `json:"SystemFaction,omitempty"`
+	Conflicts     []Conflict      `json:"Conflicts,omitempty"`
+	ThargoidWar   *ThargoidWar    `json:"ThargoidWar,omitempty"`
+}
+
+type PowerplayConflictProgress struct {
+	Power            string  `json:"Power"`
+	ConflictProgress float64 `json:"ConflictProgress"`
+}
+
+type Conflict struct {
+	WarType  string          `json:"WarType"`
+	Status   string          `json:"Status"`
+	Faction1 ConflictFaction `json:"Faction1"`
+	Faction2 ConflictFaction `json:"Faction2"`
+}
+
+type ConflictFaction struct {
+	Name    string `json:"Name"`
+	Stake   string `json:"Stake"`
+	WonDays int    `json:"WonDays"`
+}
+
+type ThargoidWar struct {
+	CurrentState           string  `json:"CurrentState"`
+	NextStateSuccess       string  `json:"NextStateSuccess"`
+	NextStateFailure       string  `json:"NextStateFailure"`
+	SuccessStateReached    bool    `json:"SuccessStateReached"`
+	WarProgress            float64 `json:"WarProgress"`
+	RemainingPorts         int     `json:"RemainingPorts"`
+	EstimatedRemainingTime string  `json:"EstimatedRemainingTime,omitempty"`
+}
+
+// StationInfo describes the station the commander is docked at.
+type StationInfo struct {
+	StationName       string           `json:"StationName,omitempty"`
+	StationType       string           `json:"StationType,omitempty"`
+	MarketID          ID               `json:"MarketID,omitempty"`
+	StationFaction    *StationFaction  `json:"StationFaction,omitempty"`
+	StationGovernment string           `json:"StationGovernment,omitempty"`
+	StationAllegiance string           `json:"StationAllegiance,omitempty"`
+	StationServices   []string         `json:"StationServices,omitempty"`
+	StationEconomy    string           `json:"StationEconomy,omitempty"`
+	StationEconomies  []StationEconomy `json:"StationEconomies,omitempty"`
+	StationState      string           `json:"StationState,omitempty"`
+	LandingPads       *LandingPads     `json:"LandingPads,omitempty"`
+	DistFromStarLS    float64          `json:"DistFromStarLS,omitempty"`
+}
+
+type LandingPads struct {
+	Small  int `json:"Small"`
+	Medium int `json:"Medium"`
+	Large  int `json:"Large"`
+}
+
+type DockedEvent struct {
+	JournalBase
+	StationInfo
+	Taxi      bool `json:"Taxi,omitempty"`
+	Multicrew bool `json:"Multicrew,omitempty"`
+}
+
+type FSDJumpEvent struct {
+	JournalBase
+	SystemInfo
+	Taxi      bool `json:"Taxi,omitempty"`
+	Multicrew bool `json:"Multicrew,omitempty"`
+}
+
+type LocationEvent struct {
+	JournalBase
+	SystemInfo
+	StationInfo
+	Docked    bool `json:"Docked"`
+	InSRV     bool `json:"InSRV,omitempty"`
+	OnFoot    bool `json:"OnFoot,omitempty"`
+	Taxi      bool `json:"Taxi,omitempty"`
+	Multicrew bool `json:"Multicrew,omitempty"`
+}
+
+type CarrierJumpEvent struct {
+	JournalBase
+	SystemInfo
+	StationInfo
+	Docked bool `json:"Docked"`
+	OnFoot bool `json:"OnFoot,omitempty"`
+}
+
+// ScanEvent covers stars, planets and belt clusters; fields that do not
+// apply to the scanned body are left zero.
+type ScanEvent struct {
+	JournalBase
+	ScanType              string             `json:"ScanType"`
+	BodyName              string             `json:"BodyName"`
+	BodyID                ID                 `json:"BodyID"`
+	Parents               []map[string]int64 `json:"Parents,omitempty"`
+	DistanceFromArrivalLS float64            `json:"DistanceFromArrivalLS"`
+	WasDiscovered         bool               `json:"WasDiscovered"`
+	WasMapped             bool               `json:"WasMapped"`
+	WasFootfalled         bool               `json:"WasFootfalled,omitempty"`
+
+	// Stars
+	StarType          string  `json:"StarType,omitempty"`
+	Subclass          int     `json:"Subclass,omitempty"`
+	StellarMass       float64 `json:"StellarMass,omitempty"`
+	AbsoluteMagnitude float64 `json:"AbsoluteMagnitude,omitempty"`
+	AgeMY             int64   `json:"Age_MY,omitempty"`
+	Luminosity        string  `json:"Luminosity,omitempty"`
+
+	// Planets and moons
+	TidalLock             bool         `json:"TidalLock,omitempty"`
+	TerraformState        string       `json:"TerraformState,omitempty"`
+	PlanetClass           string       `json:"PlanetClass,omitempty"`
+	Atmosphere            string       `json:"Atmosphere,omitempty"`
+	AtmosphereType        string       `json:"AtmosphereType,omitempty"`
+	AtmosphereComposition []Proportion `json:"AtmosphereComposition,omitempty"`
+	Volcanism             string       `json:"Volcanism,omitempty"`
+	MassEM                float64      `json:"MassEM,omitempty"`
+	SurfaceGravity        float64      `json:"SurfaceGravity,omitempty"`
+	SurfacePressure       float64      `json:"SurfacePressure,omitempty"`
+	Landable              bool         `json:"Landable,omitempty"`
+	Materials             []Proportion `json:"Materials,omitempty"`
+	Composition           *Composition `json:"Composition,omitempty"`
+	ReserveLevel          string       `json:"ReserveLevel,omitempty"`
+	Rings                 []Ring       `json:"Rings,omitempty"`
+
+	// Shared by stars and planets
+	Radius             float64 `json:"Radius,omitempty"`
+	SurfaceTemperature float64 `json:"SurfaceTemperature,omitempty"`
+	SemiMajorAxis      float64 `json:"SemiMajorAxis,omitempty"`
+	Eccentricity       float64 `json:"Eccentricity,omitempty"`
+	OrbitalInclination float64 `json:"OrbitalInclination,omitempty"`
+	Periapsis          float64 `json:"Periapsis,omitempty"`
+	OrbitalPeriod      float64 `json:"OrbitalPeriod,omitempty"`
+	AscendingNode      float64 `json:"AscendingNode,omitempty"`
+	MeanAnomaly        float64 `json:"MeanAnomaly,omitempty"`
+	RotationPeriod     float64 `json:"RotationPeriod,omitempty"`
+	AxialTilt          float64 `json:"AxialTilt,omitempty"`
+}
+
+// Proportion is a named share, used for atmosphere gases and materials.
+type Proportion struct {
+	Name    string  `json:"Name"`
+	Percent float64 `json:"Percent"`
+}
+
+type Composition struct {
+	Ice   float64 `json:"Ice"`
+	Rock  float64 `json:"Rock"`
+	Metal float64 `json:"Metal"`
+}
+
+type Ring struct {
+	Name      string  `json:"Name"`
+	RingClass string  `json:"RingClass"`
+	MassMT    float64 `json:"MassMT"`
+	InnerRad  float64 `json:"InnerRad"`
+	OuterRad  float64 `json:"OuterRad"`
+}
+
+type SAASignalsFoundEvent struct {
+	JournalBase
+	BodyName string   `json:"BodyName"`
+	BodyID   ID       `json:"BodyID"`
+	Signals  []Signal `json:"Signals"`
+	Genuses  []Genus  `json:"Genuses,omitempty"`
+}
+
+type Genus struct {
+	Genus string `json:"Genus"`
+}
+
+// CodexEntryEvent is a CodexEntry sent through journal/1 rather than the
+// dedicated codexentry/1 schema.
+type CodexEntryEvent struct {
+	JournalBase
+	System             string  `json:"System,omitempty"`
+	EntryID            ID      `json:"EntryID"`
+	Name               string  `json:"Name"`
+	Region             string  `json:"Region"`
+	Category           string  `json:"Category"`
+	SubCategory        string  `json:"SubCategory"`
+	NearestDestination string  `json:"NearestDestination,omitempty"`
+	BodyID             ID      `json:"BodyID,omitempty"`
+	BodyName           string  `json:"BodyName,omitempty"`
+	Latitude           float64 `json:"Latitude,omitempty"`
+	Longitude          float64 `json:"Longitude,omitempty"`
+}
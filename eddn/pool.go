@@ -0,0 +1,255 @@
+package eddn
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow is the policy for frames arriving while the decode queue is full.
+type Overflow int
+
+const (
+	// Block waits for room, pushing back on the relay socket.
+	Block Overflow = iota
+	// DropNewest discards the incoming frame so receiving never stalls.
+	DropNewest
+)
+
+const defaultQueueSize = 1024
+
+type job struct {
+	frame    []byte
+	received time.Time
+	// result is only used when the client is Ordered.
+	result chan *Envelope
+}
+
+// Dropped returns how many frames have been discarded under DropNewest.
+func (c *Client) Dropped() uint64 {
+	return atomic.LoadUint64(&c.dropped)
+}
+
+// Consume decodes raw zlib frames from in on a pool of workers and sends the
+// results to out until in is closed or ctx is cancelled. out is closed when
+// Consume returns. It is the same path Run uses, exposed for frames that
+// come from somewhere other than a live relay.
+func (c *Client) Consume(ctx context.Context, in <-chan []byte, out chan<- *Envelope) {
+	defer close(out)
+	if c.Tests != nil {
+		defer close(c.Tests)
+	}
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	size := c.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan job, size)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.work(ctx, queue, out)
+		}()
+	}
+
+	// OnFrame runs on its own goroutine, fed through a queue as long as the
+	// decode queue, so a slow hook only stalls admission once that fills.
+	var frames chan []byte
+	if c.OnFrame != nil {
+		frames = make(chan []byte, size)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for frame := range frames {
+				c.OnFrame(frame)
+			}
+		}()
+	}
+
+	// When ordered, every job is also admitted to the pending queue of its
+	// ordering key, which a sequencer of its own drains in arrival order.
+	pending := make(map[string]chan job)
+
+	defer wg.Wait()
+	defer close(queue)
+	defer func() {
+		for _, p := range pending {
+			close(p)
+		}
+		if frames != nil {
+			close(frames)
+		}
+	}()
+
+	for frame := range in {
+		if frames != nil {
+			if c.Overflow == DropNewest {
+				select {
+				case frames <- frame:
+				default:
+					c.drop(frame)
+					continue
+				}
+			} else {
+				select {
+				case frames <- frame:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		j := job{frame: frame, received: time.Now()}
+		if c.Ordered {
+			j.result = make(chan *Envelope, 1)
+			key := c.orderKey(frame)
+			seq := pending[key]
+			if seq == nil {
+				seq = make(chan job, size)
+				pending[key] = seq
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.sequence(ctx, seq, out)
+				}()
+			}
+			if c.Overflow == DropNewest {
+				select {
+				case seq <- j:
+				default:
+					c.drop(frame)
+					continue
+				}
+			} else {
+				select {
+				case seq <- j:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		if c.Overflow == DropNewest {
+			select {
+			case queue <- j:
+			default:
+				// The sequencer is already waiting on this job.
+				if j.result != nil {
+					j.result <- nil
+				}
+				c.drop(frame)
+			}
+			continue
+		}
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// OneSequence is an OrderBy that puts every frame in the same sequence, so
+// envelopes of all schemas leave in exactly the order their frames arrived.
+func OneSequence(frame []byte) string { return "" }
+
+// orderKey returns the key frame is sequenced under when Ordered is set.
+func (c *Client) orderKey(frame []byte) string {
+	if c.OrderBy != nil {
+		return c.OrderBy(frame)
+	}
+	return PeekSchemaRef(frame)
+}
+
+func (c *Client) drop(frame []byte) {
+	atomic.AddUint64(&c.dropped, 1)
+	if c.OnDrop != nil {
+		c.OnDrop(frame)
+	}
+}
+
+func (c *Client) work(ctx context.Context, queue <-chan job, out chan<- *Envelope) {
+	for j := range queue {
+		env, err := Decode(j.frame)
+		if err != nil {
+			c.error(err)
+		} else {
+			env.Received = j.received
+			env = c.validate(env)
+			if env != nil && c.Dedup != nil && c.Dedup.Duplicate(env) {
+				env = nil
+			}
+		}
+
+		if j.result != nil {
+			j.result <- env
+			continue
+		}
+		if !c.deliver(ctx, env, out) {
+			return
+		}
+	}
+}
+
+// validate runs the client's Validator over env and returns nil if the
+// message should be dropped.
+func (c *Client) validate(env *Envelope) *Envelope {
+	if c.Validator == nil {
+		return env
+	}
+	err := c.Validator.Validate(env)
+	if err == nil {
+		return env
+	}
+	c.error(&DecodeError{Stage: ErrValidation, SchemaRef: env.SchemaRef, Err: err})
+	if c.Strict {
+		return nil
+	}
+	env.Invalid = err
+	return env
+}
+
+// deliver sends env to out, or to Tests for /test schemas, and reports
+// false if ctx was cancelled first. nil envelopes are skipped.
+func (c *Client) deliver(ctx context.Context, env *Envelope, out chan<- *Envelope) bool {
+	if env == nil {
+		return true
+	}
+	if env.Schema.Test {
+		if c.Tests == nil {
+			return true
+		}
+		out = c.Tests
+	}
+	select {
+	case out <- env:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sequence forwards the decoded envelopes of one ordering key in the order
+// their frames arrived.
+func (c *Client) sequence(ctx context.Context, pending <-chan job, out chan<- *Envelope) {
+	for j := range pending {
+		var env *Envelope
+		select {
+		case env = <-j.result:
+		case <-ctx.Done():
+			return
+		}
+		if !c.deliver(ctx, env, out) {
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package eddn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SchemaBase is the prefix every EDDN $schemaRef starts with.
+const SchemaBase = "https://eddn.edcd.io/schemas/"
+
+// Schema identifies a $schemaRef such as
+// https://eddn.edcd.io/schemas/commodity/3/test by its family ("commodity"),
+// version (3) and whether it is the /test variant developers publish to.
+type Schema struct {
+	Family  string
+	Version int
+	Test    bool
+}
+
+// ParseSchemaRef splits ref into its parts. ok is false when ref is not an
+// EDDN schema URL.
+func ParseSchemaRef(ref string) (s Schema, ok bool) {
+	rest, found := strings.CutPrefix(ref, SchemaBase)
+	if !found {
+		return Schema{}, false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) == 3 && parts[2] == "test" {
+		s.Test = true
+		parts = parts[:2]
+	}
+	if len(parts) != 2 || parts[0] == "" {
+		return Schema{}, false
+	}
+	v, err := strconv.Atoi(parts[1])
+	if err != nil || v < 1 {
+		return Schema{}, false
+	}
+	s.Family, s.Version = parts[0], v
+	return s, true
+}
+
+// Ref formats s back into a $schemaRef.
+func (s Schema) Ref() string {
+	ref := SchemaBase + s.Family + "/" + strconv.Itoa(s.Version)
+	if s.Test {
+		ref += "/test"
+	}
+	return ref
+}
+
+// Live returns s without the test flag, for looking up resources that are
+// shared between a schema and its /test variant.
+func (s Schema) Live() Schema {
+	s.Test = false
+	return s
+}
+
+func (s Schema) String() string {
+	return fmt.Sprintf("%s/%d", s.Family, s.Version)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]map[int]func() interface{})
+)
+
+// Register makes version of a schema family decodable into the struct newMsg
+// returns. A new upstream version that is compatible with an existing struct
+// only needs another Register call, e.g.
+//
+//	eddn.Register("commodity", 4, func() interface{} { return &eddn.CommodityMessage{} })
+//
+// The /test variant of every registered version is decoded too.
+func Register(family string, version int, newMsg func() interface{}) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registry[family] == nil {
+		registry[family] = make(map[int]func() interface{})
+	}
+	registry[family][version] = newMsg
+}
+
+// Registered lists every known live schema.
+func Registered() []Schema {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var schemas []Schema
+	for family, versions := range registry {
+		for v := range versions {
+			schemas = append(schemas, Schema{Family: family, Version: v})
+		}
+	}
+	return schemas
+}
+
+// NewMessage returns a new struct of the type s decodes into, or nil if s
+// is not registered.
+func NewMessage(s Schema) interface{} {
+	if newMsg := lookup(s); newMsg != nil {
+		return newMsg()
+	}
+	return nil
+}
+
+func lookup(s Schema) func() interface{} {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[s.Family][s.Version]
+}
+
+func init() {
+	Register("fcmaterials_capi", 1, func() interface{} { return &FCMaterialsMessage{} })
+	Register("commodity", 3, func() interface{} { return &CommodityMessage{} })
+	Register("journal", 1, func() interface{} { return &JournalMessage{} })
+	Register("approachsettlement", 1, func() interface{} { return &ApproachSettlementMessage{} })
+	Register("blackmarket", 1, func() interface{} { return &BlackMarketMessage{} })
+	Register("fcmaterials_journal", 1, func() interface{} { return &FCMaterialsJournalMessage{} })
+	Register("dockinggranted", 1, func() interface{} { return &DockingGrantedMessage{} })
+	Register("outfitting", 2, func() interface{} { return &OutfittingMessage{} })
+	Register("navroute", 1, func() interface{} { return &NavRouteMessage{} })
+	Register("fsssignaldiscovered", 1, func() interface{} { return &FSSSignalDiscoveredMessage{} })
+	Register("fssallbodiesfound", 1, func() interface{} { return &FSSAllBodiesFoundMessage{} })
+	Register("scanbarycentre", 1, func() interface{} { return &ScanBaryCentreMessage{} })
+	Register("dockingdenied", 1, func() interface{} { return &DockingDeniedMessage{} })
+	Register("fssdiscoveryscan", 1, func() interface{} { return &FSSDiscoveryScanMessage{} })
+	Register("codexentry", 1, func() interface{} { return &CodexEntryMessage{} })
+	Register("shipyard", 2, func() interface{} { return &ShipyardMessage{} })
+	Register("fssbodysignals", 1, func() interface{} { return &FSSBodySignalsMessage{} })
+	Register("navbeaconscan", 1, func() interface{} { return &NavBeaconScanMessage{} })
+}
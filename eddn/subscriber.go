@@ -0,0 +1,185 @@
+package eddn
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	zmq "github.com/go-zeromq/zmq4"
+)
+
+// ConnState describes where a Subscriber is in its connection lifecycle.
+type ConnState int
+
+const (
+	Disconnected ConnState = iota
+	Connecting
+	Connected
+	Stalled
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Stalled:
+		return "stalled"
+	}
+	return fmt.Sprintf("ConnState(%d)", int(s))
+}
+
+// Subscriber keeps a SUB socket attached to an EDDN relay. When the socket
+// errors or stays silent for longer than StallTimeout it is torn down and
+// re-dialled with exponential backoff and jitter.
+type Subscriber struct {
+	Endpoint     string
+	Topic        string
+	StallTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+
+	// OnState, if set, is called on every connection state transition.
+	// err carries the reason for Disconnected and Stalled transitions.
+	OnState func(state ConnState, err error)
+
+	state ConnState
+}
+
+func NewSubscriber(endpoint string) *Subscriber {
+	return &Subscriber{
+		Endpoint:     endpoint,
+		StallTimeout: 60 * time.Second,
+		MinBackoff:   time.Second,
+		MaxBackoff:   2 * time.Minute,
+	}
+}
+
+// Run delivers raw frames to out until ctx is cancelled. It never gives up on
+// the relay; the only error it returns is ctx.Err().
+func (s *Subscriber) Run(ctx context.Context, out chan<- []byte) error {
+	attempt := 0
+	for {
+		received, _ := s.session(ctx, out)
+		if ctx.Err() != nil {
+			s.setState(Disconnected, nil)
+			return ctx.Err()
+		}
+		if received {
+			attempt = 0
+		}
+
+		wait := s.backoff(attempt)
+		attempt++
+		select {
+		case <-ctx.Done():
+			s.setState(Disconnected, nil)
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// session runs a single connection until it fails, stalls or ctx is done.
+// It reports whether any frame was received so Run can reset its backoff.
+func (s *Subscriber) session(ctx context.Context, out chan<- []byte) (bool, error) {
+	s.setState(Connecting, nil)
+
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sub := zmq.NewSub(sctx)
+	defer sub.Close()
+
+	if err := sub.Dial(s.Endpoint); err != nil {
+		s.setState(Disconnected, err)
+		return false, err
+	}
+	if err := sub.SetOption(zmq.OptionSubscribe, s.Topic); err != nil {
+		s.setState(Disconnected, err)
+		return false, err
+	}
+	s.setState(Connected, nil)
+
+	frames := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := sub.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+			if len(msg.Frames) == 0 {
+				continue
+			}
+			select {
+			case frames <- msg.Frames[0]:
+			case <-sctx.Done():
+				return
+			}
+		}
+	}()
+
+	stall := time.NewTimer(s.StallTimeout)
+	defer stall.Stop()
+
+	received := false
+	for {
+		select {
+		case <-ctx.Done():
+			return received, ctx.Err()
+		case err := <-errc:
+			s.setState(Disconnected, err)
+			return received, err
+		case <-stall.C:
+			err := fmt.Errorf("no messages for %s", s.StallTimeout)
+			s.setState(Stalled, err)
+			return received, err
+		case frame := <-frames:
+			received = true
+			select {
+			case out <- frame:
+			case <-ctx.Done():
+				return received, ctx.Err()
+			}
+			// Only restart the stall clock once the frame has been handed
+			// off, so a slow consumer is not mistaken for a dead relay.
+			if !stall.Stop() {
+				select {
+				case <-stall.C:
+				default:
+				}
+			}
+			stall.Reset(s.StallTimeout)
+		}
+	}
+}
+
+// backoff returns the delay before reconnect attempt n, doubling from
+// MinBackoff up to MaxBackoff with the upper half randomised.
+func (s *Subscriber) backoff(n int) time.Duration {
+	d := s.MinBackoff
+	for i := 0; i < n && d < s.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.MaxBackoff {
+		d = s.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (s *Subscriber) setState(state ConnState, err error) {
+	if state == s.state && err == nil {
+		return
+	}
+	s.state = state
+	if s.OnState != nil {
+		s.OnState(state, err)
+	}
+}
@@ -0,0 +1,142 @@
+package eddn
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ID is a 64-bit identifier such as a MarketID or SystemAddress. Uploaders
+// send these as integers, floats (128016640.0, 1.2801664e8) or strings;
+// all decode to the same value, and anything that is not a whole number
+// within int64 is an error rather than a silently rounded ID.
+type ID int64
+
+func (id *ID) UnmarshalJSON(data []byte) error {
+	n, err := parseInt(data)
+	if err != nil {
+		return err
+	}
+	*id = ID(n)
+	return nil
+}
+
+// Int is an integer quantity such as a price, stock level or bracket. It
+// decodes like ID; an empty string, used for unknown brackets, decodes to 0.
+type Int int64
+
+func (i *Int) UnmarshalJSON(data []byte) error {
+	n, err := parseInt(data)
+	if err != nil {
+		return err
+	}
+	*i = Int(n)
+	return nil
+}
+
+func parseInt(data []byte) (int64, error) {
+	s := string(bytes.TrimSpace(data))
+	if s == "null" {
+		return 0, nil
+	}
+	if len(s) > 0 && s[0] == '"' {
+		var str string
+		if err := json.Unmarshal([]byte(s), &str); err != nil {
+			return 0, err
+		}
+		if str == "" {
+			return 0, nil
+		}
+		s = str
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return n, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return 0, fmt.Errorf("eddn: %s overflows int64", s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("eddn: %q is not a number", s)
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("eddn: %s is not a whole number", s)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which is itself out of range.
+	if f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("eddn: %s overflows int64", s)
+	}
+	return int64(f), nil
+}
+
+// Time is a message timestamp. The game writes RFC 3339 in UTC, but some
+// uploaders drop the zone or use a space instead of the T; those parse as
+// UTC. It marshals back in RFC 3339, keeping the original offset.
+type Time struct {
+	time.Time
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// ParseTime parses s with the same tolerance as Time's JSON decoding.
+func ParseTime(s string) (Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return Time{t}, nil
+		}
+	}
+	return Time{}, fmt.Errorf("eddn: cannot parse timestamp %q", s)
+}
+
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = Time{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("eddn: timestamp must be a string, got %s", data)
+	}
+	if s == "" {
+		*t = Time{}
+		return nil
+	}
+	parsed, err := ParseTime(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte(`""`), nil
+	}
+	return []byte(strconv.Quote(t.Format(time.RFC3339Nano))), nil
+}
+
+// String formats t as RFC 3339 in UTC to the second, which sorts
+// correctly as text.
+func (t Time) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Value stores t in SQL as String does.
+func (t Time) Value() (driver.Value, error) {
+	return t.String(), nil
+}
@@ -0,0 +1,196 @@
+package eddn
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDAndInt(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  string
+	}{
+		{`128016640`, 128016640, ""},
+		{`128016640.0`, 128016640, ""},
+		{`1.2801664e8`, 128016640, ""},
+		{`"128016640"`, 128016640, ""},
+		{`"1.2801664e8"`, 128016640, ""},
+		{`-5`, -5, ""},
+		{`""`, 0, ""},
+		{`null`, 0, ""},
+		{`9223372036854775807`, 9223372036854775807, ""},
+		{`9223372036854775808`, 0, "overflows int64"},
+		{`"9223372036854775808"`, 0, "overflows int64"},
+		{`9.3e18`, 0, "overflows int64"},
+		{`-9.3e18`, 0, "overflows int64"},
+		{`1.5`, 0, "not a whole number"},
+		{`"abc"`, 0, "not a number"},
+		{`true`, 0, "not a number"},
+	}
+	for _, tt := range tests {
+		var id ID
+		err := json.Unmarshal([]byte(tt.in), &id)
+		checkInt(t, "ID", tt.in, int64(id), err, tt.want, tt.err)
+
+		var n Int
+		err = json.Unmarshal([]byte(tt.in), &n)
+		checkInt(t, "Int", tt.in, int64(n), err, tt.want, tt.err)
+	}
+}
+
+func checkInt(t *testing.T, typ, in string, got int64, err error, want int64, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s from %s: error %v, want one containing %q", typ, in, err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%s from %s: %v", typ, in, err)
+		return
+	}
+	if got != want {
+		t.Errorf("%s from %s = %d, want %d", typ, in, got, want)
+	}
+}
+
+func TestTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 9, 59, 58, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{`"2024-05-01T09:59:58Z"`, want},
+		{`"2024-05-01T09:59:58.000Z"`, want},
+		{`"2024-05-01T11:59:58+02:00"`, want},
+		{`"2024-05-01T09:59:58"`, want},
+		{`"2024-05-01 09:59:58Z"`, want},
+		{`"2024-05-01 11:59:58+02:00"`, want},
+		{`"2024-05-01 09:59:58"`, want},
+		{`"2024-05-01T09:59:58.25Z"`, want.Add(250 * time.Millisecond)},
+		{`""`, time.Time{}},
+		{`null`, time.Time{}},
+	}
+	for _, tt := range tests {
+		var got Time
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("Time from %s: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Time from %s = %v, want %v", tt.in, got.Time, tt.want)
+		}
+
+		// Marshalling keeps the instant, and the offset it was sent with.
+		b, err := json.Marshal(got)
+		if err != nil {
+			t.Errorf("marshalling %s: %v", tt.in, err)
+			continue
+		}
+		var again Time
+		if err := json.Unmarshal(b, &again); err != nil || !again.Equal(got.Time) {
+			t.Errorf("Time from %s re-marshalled as %s, decodes to %v (%v)", tt.in, b, again.Time, err)
+		}
+	}
+
+	for _, in := range []string{`"yesterday"`, `"2024-05-01"`, `12345`} {
+		var got Time
+		if err := json.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("Time from %s: no error", in)
+		}
+	}
+}
+
+func TestTimeString(t *testing.T) {
+	got, err := ParseTime("2024-05-01T11:59:58.5+02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := got.String(); s != "2024-05-01T09:59:58Z" {
+		t.Errorf("String() = %q, want UTC to the second", s)
+	}
+	if s := (Time{}).String(); s != "" {
+		t.Errorf("zero String() = %q, want empty", s)
+	}
+}
+
+// roundTrip decodes in into a new value of v's type, re-encodes it, decodes
+// that again and reports whether both decodes are equal.
+func roundTrip(t *testing.T, in string, v interface{}) {
+	t.Helper()
+	first := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	if err := json.Unmarshal([]byte(in), first); err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	b, err := json.Marshal(first)
+	if err != nil {
+		t.Fatalf("encoding: %v", err)
+	}
+	second := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	if err := json.Unmarshal(b, second); err != nil {
+		t.Fatalf("decoding %s: %v", b, err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("round trip changed the message:\n first  %+v\n second %+v\n via %s", first, second, b)
+	}
+}
+
+func TestCommodityRoundTrip(t *testing.T) {
+	in := `{
+		"systemName": "Sol", "stationName": "Abraham Lincoln", "stationType": "Orbis",
+		"marketId": 128016640.0, "timestamp": "2024-05-01T09:59:58Z",
+		"commodities": [
+			{"name": "gold", "meanPrice": 3, "buyPrice": 1, "stock": 0, "stockBracket": 0,
+			 "sellPrice": 2, "demand": 0, "demandBracket": 0},
+			{"name": "silver", "meanPrice": "40", "buyPrice": 30, "stock": 1.2e3, "stockBracket": "",
+			 "sellPrice": 35, "demand": 9, "demandBracket": 2}
+		],
+		"economies": [{"name": "Service", "proportion": 1}],
+		"prohibited": ["Slaves"]
+	}`
+	roundTrip(t, in, &CommodityMessage{})
+
+	// Zero quantities are required by the schema and must be written out.
+	var msg CommodityMessage
+	if err := json.Unmarshal([]byte(in), &msg); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(msg.Commodities[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"meanPrice", "buyPrice", "stock", "stockBracket", "sellPrice", "demand", "demandBracket"} {
+		if !strings.Contains(string(b), `"`+field+`":`) {
+			t.Errorf("%s missing from %s", field, b)
+		}
+	}
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	in := `{
+		"timestamp": "2024-05-01T10:00:00Z", "event": "Docked",
+		"StarSystem": "Sol", "StarPos": [0, 0, 0], "SystemAddress": "10477373803",
+		"StationName": "Galileo", "StationType": "Ocellus", "MarketID": 128016641,
+		"StationServices": ["dock", "materialtrader"],
+		"LandingPads": {"Small": 4, "Medium": 8, "Large": 4},
+		"DistFromStarLS": 500.5, "horizons": true, "odyssey": true
+	}`
+	roundTrip(t, in, &JournalMessage{})
+
+	var msg JournalMessage
+	if err := json.Unmarshal([]byte(in), &msg); err != nil {
+		t.Fatal(err)
+	}
+	docked, ok := msg.Detail.(*DockedEvent)
+	if !ok {
+		t.Fatalf("Detail is %T, want *DockedEvent", msg.Detail)
+	}
+	if docked.MarketID != 128016641 || docked.SystemAddress != 10477373803 {
+		t.Errorf("Docked IDs = %d, %d", docked.MarketID, docked.SystemAddress)
+	}
+}
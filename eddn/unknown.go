@@ -0,0 +1,253 @@
+package eddn
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message structs keep fields they do not declare in an Extra field:
+//
+//	Extra map[string]json.RawMessage `json:"-"`
+//
+// DecodeJSON fills it, and lists every undeclared field, nested ones
+// included, in Envelope.Unknown.
+
+var extraType = reflect.TypeOf(map[string]json.RawMessage(nil))
+
+// unknownFields compares the object in raw against the struct v points to,
+// or the one it declares it is shaped like. Undeclared top level fields are
+// stored in v's Extra field, if it has one, and every undeclared path, e.g.
+// "commodities[].statusFlags", is returned.
+func unknownFields(raw json.RawMessage, v interface{}) []string {
+	shape := v
+	if d, ok := v.(interface{ declared() interface{} }); ok {
+		shape = d.declared()
+	}
+	var paths []string
+	extra := walkUnknown(raw, reflect.TypeOf(shape), "", &paths)
+	if len(extra) > 0 {
+		if f := reflect.ValueOf(v).Elem().FieldByName("Extra"); f.IsValid() && f.Type() == extraType {
+			f.Set(reflect.ValueOf(extra))
+		}
+	}
+	return paths
+}
+
+// walkUnknown appends the undeclared paths in raw to paths and returns the
+// undeclared fields of the object at this level.
+func walkUnknown(raw json.RawMessage, t reflect.Type, path string, paths *[]string) map[string]json.RawMessage {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil
+	}
+
+	switch {
+	case raw[0] == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return nil
+		}
+		for _, item := range items {
+			walkUnknown(item, t.Elem(), path+"[]", paths)
+		}
+		return nil
+	case raw[0] == '{' && t.Kind() == reflect.Slice:
+		// A list sent as an object keyed by id, like the CAPI sales.
+		var items map[string]json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return nil
+		}
+		for _, item := range items {
+			walkUnknown(item, t.Elem(), path+"[]", paths)
+		}
+		return nil
+	case raw[0] != '{' || t.Kind() != reflect.Struct:
+		return nil
+	}
+
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) != nil {
+		return nil
+	}
+	known := JSONFields(t)
+	var extra map[string]json.RawMessage
+	for key, value := range obj {
+		p := key
+		if path != "" {
+			p = path + "." + key
+		}
+		f, ok := known[strings.ToLower(key)]
+		if !ok {
+			if extra == nil {
+				extra = make(map[string]json.RawMessage)
+			}
+			extra[key] = value
+			*paths = append(*paths, p)
+			continue
+		}
+		walkUnknown(value, f.Type, p, paths)
+	}
+	return extra
+}
+
+var fieldCache sync.Map // reflect.Type -> map[string]JSONField
+
+// JSONField is a struct field as encoding/json sees it: its JSON name and
+// its type.
+type JSONField struct {
+	Name string
+	Type reflect.Type
+}
+
+// JSONFields maps the lower cased JSON names of t's fields, including those
+// of embedded structs, to the fields. Lower casing mirrors encoding/json's
+// case-insensitive matching. The map is shared and must not be modified.
+func JSONFields(t reflect.Type) map[string]JSONField {
+	if m, ok := fieldCache.Load(t); ok {
+		return m.(map[string]JSONField)
+	}
+	m := make(map[string]JSONField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range JSONFields(ft) {
+					if _, ok := m[k]; !ok {
+						m[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		m[strings.ToLower(name)] = JSONField{name, f.Type}
+	}
+	fieldCache.Store(t, m)
+	return m
+}
+
+// UnknownField is one entry in a FieldReport.
+type UnknownField struct {
+	// Schema is the schema family and version, plus the event for
+	// journal-style messages, e.g. "journal/1 FSDJump".
+	Schema    string    `json:"schema"`
+	Path      string    `json:"path"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Example is a value from the most recent sighting.
+	Example json.RawMessage `json:"example,omitempty"`
+}
+
+// FieldReport keeps a rolling tally of fields the message structs do not
+// declare, so it is obvious when Schemas.go needs updating. Register
+// Observe with OnAny. It is safe for concurrent use.
+type FieldReport struct {
+	// Window is how long a field is reported after it was last seen; zero
+	// keeps everything.
+	Window time.Duration
+
+	mu     sync.Mutex
+	fields map[[2]string]*UnknownField
+}
+
+func NewFieldReport(window time.Duration) *FieldReport {
+	return &FieldReport{Window: window, fields: make(map[[2]string]*UnknownField)}
+}
+
+// Observe records env's unknown fields.
+func (r *FieldReport) Observe(env *Envelope) {
+	if len(env.Unknown) == 0 {
+		return
+	}
+	schema := env.Schema.String()
+	if ev := eventOf(env.Message); ev != "" {
+		schema += " " + ev
+	}
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, path := range env.Unknown {
+		key := [2]string{schema, path}
+		f, ok := r.fields[key]
+		if !ok {
+			f = &UnknownField{Schema: schema, Path: path, FirstSeen: now}
+			r.fields[key] = f
+		}
+		f.Count++
+		f.LastSeen = now
+		if !strings.ContainsAny(path, ".[") {
+			f.Example = env.extra(path)
+		}
+	}
+}
+
+// Snapshot returns the fields seen within the window, by schema and path.
+func (r *FieldReport) Snapshot() []UnknownField {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []UnknownField
+	for key, f := range r.fields {
+		if r.Window > 0 && time.Since(f.LastSeen) > r.Window {
+			delete(r.fields, key)
+			continue
+		}
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Schema != out[j].Schema {
+			return out[i].Schema < out[j].Schema
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+// extra returns the top level undeclared field name from env.Message.
+func (env *Envelope) extra(name string) json.RawMessage {
+	v := reflect.ValueOf(env.Message)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.Elem().FieldByName("Extra")
+	if !f.IsValid() || f.Type() != extraType {
+		return nil
+	}
+	return f.Interface().(map[string]json.RawMessage)[name]
+}
+
+// eventOf returns the Event field of journal-style messages.
+func eventOf(msg interface{}) string {
+	v := reflect.ValueOf(msg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.Elem().FieldByName("Event")
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
@@ -0,0 +1,118 @@
+// Package galaxy keeps a catalogue of star systems seen on EDDN with a
+// spatial index for proximity queries.
+package galaxy
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// System is a star system with known coordinates.
+type System struct {
+	Address int64      `json:"systemAddress"`
+	Name    string     `json:"name"`
+	Pos     [3]float64 `json:"starPos"`
+}
+
+// Catalogue maps SystemAddress to System. The spatial index is rebuilt
+// lazily on the first query after the catalogue changes.
+type Catalogue struct {
+	mu      sync.RWMutex
+	systems map[int64]System
+	names   map[string]int64
+	tree    *kdTree
+}
+
+func NewCatalogue() *Catalogue {
+	return &Catalogue{
+		systems: make(map[int64]System),
+		names:   make(map[string]int64),
+	}
+}
+
+// Add records or updates a system. Systems without an address or name are
+// ignored. It reports whether the catalogue changed.
+func (c *Catalogue) Add(sys System) bool {
+	if sys.Address == 0 || sys.Name == "" {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.systems[sys.Address]; ok {
+		if old == sys {
+			return false
+		}
+		// Another system may have taken the old name since.
+		if key := strings.ToLower(old.Name); c.names[key] == old.Address {
+			delete(c.names, key)
+		}
+	}
+	c.systems[sys.Address] = sys
+	c.names[strings.ToLower(sys.Name)] = sys.Address
+	c.tree = nil
+	return true
+}
+
+func (c *Catalogue) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.systems)
+}
+
+func (c *Catalogue) ByAddress(address int64) (System, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	sys, ok := c.systems[address]
+	return sys, ok
+}
+
+// ByName looks a system up case-insensitively.
+func (c *Catalogue) ByName(name string) (System, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	address, ok := c.names[strings.ToLower(name)]
+	if !ok {
+		return System{}, false
+	}
+	return c.systems[address], true
+}
+
+// Nearest returns up to n systems closest to pos, nearest first.
+func (c *Catalogue) Nearest(pos [3]float64, n int) []System {
+	return c.index().nearest(pos, n)
+}
+
+// Within returns every system within radius light years of pos, nearest first.
+func (c *Catalogue) Within(pos [3]float64, radius float64) []System {
+	out := c.index().within(pos, radius, nil)
+	sort.Slice(out, func(i, j int) bool { return dist2(out[i].Pos, pos) < dist2(out[j].Pos, pos) })
+	return out
+}
+
+func (c *Catalogue) index() *kdTree {
+	c.mu.RLock()
+	t := c.tree
+	c.mu.RUnlock()
+	if t != nil {
+		return t
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tree == nil {
+		systems := make([]System, 0, len(c.systems))
+		for _, sys := range c.systems {
+			systems = append(systems, sys)
+		}
+		c.tree = buildKDTree(systems)
+	}
+	return c.tree
+}
+
+// Distance returns the distance in light years between two StarPos values.
+func Distance(a, b [3]float64) float64 {
+	return math.Sqrt(dist2(a, b))
+}
@@ -0,0 +1,66 @@
+package galaxy
+
+import "testing"
+
+func TestCatalogueRename(t *testing.T) {
+	c := NewCatalogue()
+	sol := System{Address: 10477373803, Name: "Sol", Pos: [3]float64{0, 0, 0}}
+
+	if !c.Add(sol) {
+		t.Fatal("adding Sol reported no change")
+	}
+	if c.Add(sol) {
+		t.Error("adding Sol again reported a change")
+	}
+
+	renamed := sol
+	renamed.Name = "Sol Prime"
+	if !c.Add(renamed) {
+		t.Fatal("renaming reported no change")
+	}
+	if _, ok := c.ByName("Sol"); ok {
+		t.Error("old name still found after rename")
+	}
+	if got, ok := c.ByName("sol prime"); !ok || got.Address != sol.Address {
+		t.Errorf("ByName(sol prime) = %+v, %v", got, ok)
+	}
+
+	if !c.Add(sol) {
+		t.Fatal("renaming back reported no change")
+	}
+	if _, ok := c.ByName("Sol Prime"); ok {
+		t.Error("name from before renaming back still found")
+	}
+	if got, ok := c.ByName("SOL"); !ok || got.Address != sol.Address {
+		t.Errorf("ByName(SOL) = %+v, %v", got, ok)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len = %d, want 1", c.Len())
+	}
+}
+
+func TestCatalogueRenameKeepsTakenName(t *testing.T) {
+	c := NewCatalogue()
+	c.Add(System{Address: 1, Name: "Hyades Sector AB-C d1"})
+	// Another address reports the same name, e.g. after a data fix.
+	c.Add(System{Address: 2, Name: "Hyades Sector AB-C d1"})
+	// Renaming the first must not take the name away from the second.
+	c.Add(System{Address: 1, Name: "Hyades Sector AB-C d2"})
+
+	if got, ok := c.ByName("Hyades Sector AB-C d1"); !ok || got.Address != 2 {
+		t.Errorf("ByName(d1) = %+v, %v, want address 2", got, ok)
+	}
+	if got, ok := c.ByName("Hyades Sector AB-C d2"); !ok || got.Address != 1 {
+		t.Errorf("ByName(d2) = %+v, %v, want address 1", got, ok)
+	}
+}
+
+func TestCatalogueIgnoresIncomplete(t *testing.T) {
+	c := NewCatalogue()
+	if c.Add(System{Name: "Sol"}) || c.Add(System{Address: 1}) {
+		t.Error("systems without an address or name were added")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len = %d, want 0", c.Len())
+	}
+}
@@ -0,0 +1,119 @@
+package galaxy
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// kdTree is a static 3-d tree over systems, stored implicitly: the median of
+// each slice is the node and the halves on either side are its children.
+type kdTree struct {
+	nodes []System
+}
+
+func buildKDTree(systems []System) *kdTree {
+	t := &kdTree{nodes: systems}
+	t.build(0, len(systems), 0)
+	return t
+}
+
+func (t *kdTree) build(lo, hi, axis int) {
+	if hi-lo <= 1 {
+		return
+	}
+	part := t.nodes[lo:hi]
+	sort.Slice(part, func(i, j int) bool { return part[i].Pos[axis] < part[j].Pos[axis] })
+	mid := (lo + hi) / 2
+	next := (axis + 1) % 3
+	t.build(lo, mid, next)
+	t.build(mid+1, hi, next)
+}
+
+// within appends every system no further than radius from p.
+func (t *kdTree) within(p [3]float64, radius float64, out []System) []System {
+	return t.withinRange(0, len(t.nodes), 0, p, radius*radius, out)
+}
+
+func (t *kdTree) withinRange(lo, hi, axis int, p [3]float64, r2 float64, out []System) []System {
+	if lo >= hi {
+		return out
+	}
+	mid := (lo + hi) / 2
+	n := t.nodes[mid]
+	if dist2(n.Pos, p) <= r2 {
+		out = append(out, n)
+	}
+	d := p[axis] - n.Pos[axis]
+	next := (axis + 1) % 3
+	if d <= 0 || d*d <= r2 {
+		out = t.withinRange(lo, mid, next, p, r2, out)
+	}
+	if d >= 0 || d*d <= r2 {
+		out = t.withinRange(mid+1, hi, next, p, r2, out)
+	}
+	return out
+}
+
+// nearest returns up to k systems closest to p, nearest first.
+func (t *kdTree) nearest(p [3]float64, k int) []System {
+	if k <= 0 {
+		return nil
+	}
+	h := &maxHeap{}
+	t.nearestRange(0, len(t.nodes), 0, p, k, h)
+
+	out := make([]System, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(h).(candidate).sys
+	}
+	return out
+}
+
+func (t *kdTree) nearestRange(lo, hi, axis int, p [3]float64, k int, h *maxHeap) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	n := t.nodes[mid]
+	if d2 := dist2(n.Pos, p); h.Len() < k {
+		heap.Push(h, candidate{n, d2})
+	} else if d2 < (*h)[0].d2 {
+		(*h)[0] = candidate{n, d2}
+		heap.Fix(h, 0)
+	}
+
+	d := p[axis] - n.Pos[axis]
+	near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+	if d > 0 {
+		near, far = far, near
+	}
+	next := (axis + 1) % 3
+	t.nearestRange(near[0], near[1], next, p, k, h)
+	if h.Len() < k || d*d < (*h)[0].d2 {
+		t.nearestRange(far[0], far[1], next, p, k, h)
+	}
+}
+
+type candidate struct {
+	sys System
+	d2  float64
+}
+
+// maxHeap keeps the furthest of the current best candidates on top.
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].d2 > h[j].d2 }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+func dist2(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
@@ -0,0 +1,109 @@
+package galaxy
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomSystems returns n systems on a coarse integer grid, so many share a
+// distance from any query point and some lie exactly on a query radius.
+func randomSystems(r *rand.Rand, n int) []System {
+	systems := make([]System, n)
+	for i := range systems {
+		systems[i] = System{
+			Address: int64(i + 1),
+			Name:    string(rune('A'+i%26)) + string(rune('a'+i/26%26)),
+			Pos:     [3]float64{float64(r.Intn(21) - 10), float64(r.Intn(21) - 10), float64(r.Intn(21) - 10)},
+		}
+	}
+	return systems
+}
+
+func bruteDistances(systems []System, p [3]float64) []float64 {
+	d := make([]float64, len(systems))
+	for i, s := range systems {
+		d[i] = dist2(s.Pos, p)
+	}
+	sort.Float64s(d)
+	return d
+}
+
+func TestNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 7, 100, 1000} {
+		systems := randomSystems(r, n)
+		tree := buildKDTree(append([]System(nil), systems...))
+		for q := 0; q < 50; q++ {
+			p := [3]float64{float64(r.Intn(25) - 12), float64(r.Intn(25) - 12), float64(r.Intn(25) - 12)}
+			if q%2 == 1 {
+				p[0] += r.Float64()
+			}
+			want := bruteDistances(systems, p)
+			for _, k := range []int{0, 1, 5, n, n + 3} {
+				got := tree.nearest(p, k)
+				wantN := k
+				if wantN > n {
+					wantN = n
+				}
+				if len(got) != wantN {
+					t.Fatalf("n=%d k=%d: got %d systems, want %d", n, k, len(got), wantN)
+				}
+				// With ties any of the equally near systems will do, so
+				// compare distances rather than identities.
+				for i, s := range got {
+					if d := dist2(s.Pos, p); d != want[i] {
+						t.Fatalf("n=%d k=%d at %v: result %d is %v away, want %v", n, k, p, i, d, want[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWithin(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	systems := randomSystems(r, 500)
+	tree := buildKDTree(append([]System(nil), systems...))
+	for q := 0; q < 100; q++ {
+		p := [3]float64{float64(r.Intn(21) - 10), float64(r.Intn(21) - 10), float64(r.Intn(21) - 10)}
+		// Whole radii on a whole grid put systems exactly on the edge,
+		// such as at (3, 4, 0) from the centre for a radius of 5.
+		for _, radius := range []float64{0, 1, 5, 7.5, 40} {
+			want := make(map[int64]bool)
+			for _, s := range systems {
+				if dist2(s.Pos, p) <= radius*radius {
+					want[s.Address] = true
+				}
+			}
+			got := tree.within(p, radius, nil)
+			seen := make(map[int64]bool)
+			for _, s := range got {
+				if !want[s.Address] || seen[s.Address] {
+					t.Fatalf("within(%v, %v) returned %v at %v", p, radius, s.Name, s.Pos)
+				}
+				seen[s.Address] = true
+			}
+			if len(seen) != len(want) {
+				t.Fatalf("within(%v, %v) returned %d systems, want %d", p, radius, len(seen), len(want))
+			}
+		}
+	}
+}
+
+func TestCatalogueWithinSorted(t *testing.T) {
+	c := NewCatalogue()
+	for _, s := range randomSystems(rand.New(rand.NewSource(3)), 200) {
+		c.Add(s)
+	}
+	p := [3]float64{1, 2, 3}
+	got := c.Within(p, 6)
+	if len(got) == 0 {
+		t.Fatal("no systems within 6 ly")
+	}
+	for i := 1; i < len(got); i++ {
+		if dist2(got[i-1].Pos, p) > dist2(got[i].Pos, p) {
+			t.Fatalf("Within not nearest first: %v before %v", got[i-1].Pos, got[i].Pos)
+		}
+	}
+}
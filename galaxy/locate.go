@@ -0,0 +1,41 @@
+package galaxy
+
+import "EDDN/eddn"
+
+// Locate extracts every system position carried by a decoded message.
+func Locate(msg interface{}) []System {
+	switch v := msg.(type) {
+	case *eddn.JournalMessage:
+		return one(v.SystemAddress, v.StarSystem, v.StarPos)
+	case *eddn.NavRouteMessage:
+		out := make([]System, 0, len(v.Route))
+		for _, hop := range v.Route {
+			out = append(out, one(hop.SystemAddress, hop.StarSystem, hop.StarPos)...)
+		}
+		return out
+	case *eddn.ApproachSettlementMessage:
+		return one(v.SystemAddress, v.StarSystem, v.StarPos)
+	case *eddn.FSSSignalDiscoveredMessage:
+		return one(v.SystemAddress, v.StarSystem, v.StarPos)
+	case *eddn.FSSAllBodiesFoundMessage:
+		return one(v.SystemAddress, v.SystemName, v.StarPos)
+	case *eddn.ScanBaryCentreMessage:
+		return one(v.SystemAddress, v.StarSystem, v.StarPos)
+	case *eddn.FSSDiscoveryScanMessage:
+		return one(v.SystemAddress, v.SystemName, v.StarPos)
+	case *eddn.CodexEntryMessage:
+		return one(v.SystemAddress, v.System, v.StarPos)
+	case *eddn.FSSBodySignalsMessage:
+		return one(v.SystemAddress, v.StarSystem, v.StarPos)
+	case *eddn.NavBeaconScanMessage:
+		return one(v.SystemAddress, v.StarSystem, v.StarPos)
+	}
+	return nil
+}
+
+func one(address eddn.ID, name string, pos [3]float64) []System {
+	if address == 0 || name == "" {
+		return nil
+	}
+	return []System{{Address: int64(address), Name: name, Pos: pos}}
+}
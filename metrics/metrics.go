@@ -0,0 +1,155 @@
+// Package metrics exposes pipeline health as Prometheus metrics.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"EDDN/eddn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	framesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "eddn_frames_received_total",
+		Help: "Raw frames received before decoding.",
+	})
+	framesDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "eddn_frames_dropped_total",
+		Help: "Frames discarded because the decode queue was full.",
+	})
+	decompressFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "eddn_decompress_failures_total",
+		Help: "Frames that could not be zlib decompressed.",
+	})
+	invalidJSON = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "eddn_invalid_json_total",
+		Help: "Decompressed frames that were not valid JSON.",
+	})
+	envelopeFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "eddn_envelope_failures_total",
+		Help: "Messages whose EDDN envelope could not be parsed.",
+	})
+	unknownSchemas = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "eddn_unknown_schema_total",
+		Help: "Messages with a $schemaRef the decoder does not know.",
+	})
+	unmarshalFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "eddn_unmarshal_failures_total",
+		Help: "Messages that did not fit their schema's struct.",
+	}, []string{"schema"})
+	validationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "eddn_validation_failures_total",
+		Help: "Messages that failed JSON Schema validation.",
+	}, []string{"schema"})
+	duplicates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "eddn_duplicates_total",
+		Help: "Messages suppressed as repeats of one seen recently.",
+	}, []string{"schema", "software"})
+	messages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "eddn_messages_total",
+		Help: "Successfully decoded messages.",
+	}, []string{"schema"})
+	software = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "eddn_messages_by_software_total",
+		Help: "Successfully decoded messages by uploading software.",
+	}, []string{"software"})
+	latency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "eddn_gateway_latency_seconds",
+		Help:    "Time from the gateway timestamp to receipt.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+	}, []string{"schema"})
+)
+
+// Frame counts a raw frame. Use it as, or from, eddn.Client.OnFrame.
+func Frame(frame []byte) {
+	framesReceived.Inc()
+}
+
+// Drop counts a frame discarded by the decode pool.
+func Drop(frame []byte) {
+	framesDropped.Inc()
+}
+
+// Error classifies a decode failure. Use it from eddn.Client.OnError.
+func Error(err error) {
+	var de *eddn.DecodeError
+	if !errors.As(err, &de) {
+		return
+	}
+	switch {
+	case errors.Is(err, eddn.ErrDecompress):
+		decompressFailures.Inc()
+	case errors.Is(err, eddn.ErrInvalidJSON):
+		invalidJSON.Inc()
+	case errors.Is(err, eddn.ErrEnvelope):
+		envelopeFailures.Inc()
+	case errors.Is(err, eddn.ErrUnknownSchema):
+		unknownSchemas.Inc()
+	case errors.Is(err, eddn.ErrMessage):
+		unmarshalFailures.WithLabelValues(de.SchemaRef).Inc()
+	case errors.Is(err, eddn.ErrValidation):
+		validationFailures.WithLabelValues(de.SchemaRef).Inc()
+	}
+}
+
+// Message records a successfully decoded envelope.
+func Message(env *eddn.Envelope) {
+	messages.WithLabelValues(env.SchemaRef).Inc()
+	software.WithLabelValues(env.Header.SoftwareName).Inc()
+	if !env.Header.GatewayTimestamp.IsZero() && !env.Received.IsZero() {
+		latency.WithLabelValues(env.SchemaRef).Observe(env.Received.Sub(env.Header.GatewayTimestamp).Seconds())
+	}
+}
+
+// Duplicate counts a message suppressed by an eddn.Deduper.
+func Duplicate(env *eddn.Envelope) {
+	duplicates.WithLabelValues(env.SchemaRef, env.Header.SoftwareName).Inc()
+}
+
+// Attach wires the collectors into a client and dispatcher. The client's
+// existing hooks keep running.
+func Attach(c *eddn.Client, d *eddn.Dispatcher) {
+	onFrame, onError, onDrop := c.OnFrame, c.OnError, c.OnDrop
+	c.OnFrame = func(frame []byte) {
+		Frame(frame)
+		if onFrame != nil {
+			onFrame(frame)
+		}
+	}
+	c.OnError = func(err error) {
+		Error(err)
+		if onError != nil {
+			onError(err)
+			return
+		}
+		eddn.LogError(err)
+	}
+	c.OnDrop = func(frame []byte) {
+		Drop(frame)
+		if onDrop != nil {
+			onDrop(frame)
+		}
+	}
+	if c.Dedup != nil {
+		onDuplicate := c.Dedup.OnDuplicate
+		c.Dedup.OnDuplicate = func(env *eddn.Envelope) {
+			Duplicate(env)
+			if onDuplicate != nil {
+				onDuplicate(env)
+			}
+		}
+	}
+	d.HandleAny(Message)
+}
+
+// Serve exposes /metrics on addr. It only returns on error.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return srv.ListenAndServe()
+}
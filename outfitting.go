@@ -0,0 +1,78 @@
+package main
+
+import (
+	"EDDN/outfitting"
+	"EDDN/store"
+	"flag"
+	"fmt"
+	"log"
+)
+
+func findOutfitting(args []string) {
+	fs := flag.NewFlagSet("outfitting", flag.ExitOnError)
+	db := fs.String("db", "eddn.db", "SQLite database written by listen -db")
+	module := fs.String("module", "", "module symbol or name to look for, e.g. hpt_pulselaser_fixed_small or \"5A Frame Shift Drive\"")
+	ship := fs.String("ship", "", "ship symbol or name to look for, e.g. anaconda or \"Krait Mk II\"")
+	from := fs.String("from", "", "list the stations nearest this system instead of the most recently seen")
+	limit := fs.Int("limit", 10, "number of stations to show")
+	fs.Parse(args)
+
+	kind, query, name := store.Modules, *module, outfitting.ModuleName
+	if *ship != "" {
+		kind, query, name = store.Ships, *ship, outfitting.ShipName
+	}
+	if query == "" {
+		log.Fatal("outfitting: -module or -ship is required")
+	}
+
+	st, err := store.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	stocked, err := st.StockedSymbols(kind)
+	if err != nil {
+		log.Fatal(err)
+	}
+	symbols := outfitting.Resolve(query, stocked, name)
+	if len(symbols) == 0 {
+		fmt.Printf("No station sells %s.\n", query)
+		return
+	}
+
+	var centre *[3]float64
+	if *from != "" {
+		sys, err := st.SystemByName(*from)
+		if store.IsNotFound(err) {
+			log.Fatalf("outfitting: position of %s is not known", *from)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		centre = &sys.Pos
+	}
+
+	for _, sym := range symbols {
+		var stockists []store.Stockist
+		if centre != nil {
+			stockists, err = st.NearestStockists(kind, sym, *centre, *limit)
+		} else {
+			stockists, err = st.Stockists(kind, sym, *limit)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s (%s):\n", name(sym), sym)
+		if len(stockists) == 0 {
+			fmt.Println("   no station with a known position")
+		}
+		for i, s := range stockists {
+			if s.Distance != nil {
+				fmt.Printf("%3d. %s / %s, %.1f ly (%s)\n", i+1, s.SystemName, s.StationName, *s.Distance, s.Timestamp)
+				continue
+			}
+			fmt.Printf("%3d. %s / %s (%s)\n", i+1, s.SystemName, s.StationName, s.Timestamp)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// Package outfitting turns the module and ship symbols sent in outfitting
+// and shipyard messages into the names players see in game.
+package outfitting
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Module is a decoded outfitting symbol. Fields the symbol does not carry
+// are left empty: weapons have a mount and size, most internals a class and
+// rating, armour a grade.
+type Module struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+	// Class is the module size, 0 for utility mounts.
+	Class  int    `json:"class,omitempty"`
+	Rating string `json:"rating,omitempty"`
+	Mount  string `json:"mount,omitempty"`
+	// Size is the hardpoint size: Small, Medium, Large, Huge or Tiny for
+	// utility mounts.
+	Size string `json:"size,omitempty"`
+	// Ship is set for armour, which is specific to a ship.
+	Ship string `json:"ship,omitempty"`
+}
+
+// String formats the module as the outfitting screen does, e.g.
+// "5A Frame Shift Drive" or "Pulse Laser (Fixed, Small)".
+func (m Module) String() string {
+	switch {
+	case m.Ship != "":
+		return m.Ship + " " + m.Name
+	case m.Mount != "":
+		return m.Name + " (" + m.Mount + ", " + m.Size + ")"
+	case m.Rating != "":
+		return strconv.Itoa(m.Class) + m.Rating + " " + m.Name
+	}
+	return m.Name
+}
+
+var mounts = map[string]string{
+	"fixed":  "Fixed",
+	"gimbal": "Gimballed",
+	"turret": "Turreted",
+}
+
+var sizes = map[string]string{
+	"tiny":   "Tiny",
+	"small":  "Small",
+	"medium": "Medium",
+	"large":  "Large",
+	"huge":   "Huge",
+}
+
+// ratings maps the classN suffix to the letter rating; class5 is A.
+var ratings = map[string]string{
+	"class1": "E",
+	"class2": "D",
+	"class3": "C",
+	"class4": "B",
+	"class5": "A",
+}
+
+var armour = map[string]string{
+	"grade1":   "Lightweight Alloy",
+	"grade2":   "Reinforced Alloy",
+	"grade3":   "Military Grade Composite",
+	"mirrored": "Mirrored Surface Composite",
+	"reactive": "Reactive Surface Composite",
+}
+
+// moduleNames maps the name part of a symbol, the words between the hpt_ or
+// int_ prefix and the mount or size, to its in game name.
+var moduleNames = map[string]string{
+	// Hardpoints
+	"pulselaser":               "Pulse Laser",
+	"pulselaserburst":          "Burst Laser",
+	"beamlaser":                "Beam Laser",
+	"multicannon":              "Multi-Cannon",
+	"cannon":                   "Cannon",
+	"slugshot":                 "Fragment Cannon",
+	"railgun":                  "Rail Gun",
+	"plasmaaccelerator":        "Plasma Accelerator",
+	"dumbfiremissilerack":      "Missile Rack",
+	"basicmissilerack":         "Seeker Missile Rack",
+	"drunkmissilerack":         "Pack-Hound Missile Rack",
+	"advancedtorppylon":        "Torpedo Pylon",
+	"minelauncher":             "Mine Launcher",
+	"mininglaser":              "Mining Laser",
+	"mining_abrblstr":          "Abrasion Blaster",
+	"mining_seismchrgwarhd":    "Seismic Charge Launcher",
+	"mining_subsurfdispmisle":  "Sub-Surface Displacement Missile",
+	"flakmortar":               "Remote Release Flak Launcher",
+	"flechettelauncher":        "Remote Release Flechette Launcher",
+	"guardian_gausscannon":     "Guardian Gauss Cannon",
+	"guardian_plasmalauncher":  "Guardian Plasma Charger",
+	"guardian_shardcannon":     "Guardian Shard Cannon",
+	"atdumbfiremissile":        "AX Missile Rack",
+	"atmulticannon":            "AX Multi-Cannon",
+	"causticmissile":           "Enzyme Missile Rack",
+	"shieldbooster":            "Shield Booster",
+	"chafflauncher":            "Chaff Launcher",
+	"heatsinklauncher":         "Heat Sink Launcher",
+	"plasmapointdefence":       "Point Defence",
+	"electroniccountermeasure": "Electronic Countermeasure",
+	"cargoscanner":             "Manifest Scanner",
+	"cloudscanner":             "Frame Shift Wake Scanner",
+	"crimescanner":             "Kill Warrant Scanner",
+	"antiunknownshutdown":      "Shutdown Field Neutraliser",
+	"mrascanner":               "Pulse Wave Analyser",
+	"xenoscanner":              "Xeno Scanner",
+
+	// Internals
+	"powerplant":                     "Power Plant",
+	"engine":                         "Thrusters",
+	"hyperdrive":                     "Frame Shift Drive",
+	"hyperdrive_overcharge":          "Frame Shift Drive (SCO)",
+	"lifesupport":                    "Life Support",
+	"powerdistributor":               "Power Distributor",
+	"sensors":                        "Sensors",
+	"fueltank":                       "Fuel Tank",
+	"cargorack":                      "Cargo Rack",
+	"corrosionproofcargorack":        "Corrosion Resistant Cargo Rack",
+	"shieldgenerator":                "Shield Generator",
+	"shieldcellbank":                 "Shield Cell Bank",
+	"fuelscoop":                      "Fuel Scoop",
+	"refinery":                       "Refinery",
+	"repairer":                       "Auto Field-Maintenance Unit",
+	"hullreinforcement":              "Hull Reinforcement Package",
+	"metaalloyhullreinforcement":     "Meta Alloy Hull Reinforcement",
+	"modulereinforcement":            "Module Reinforcement Package",
+	"detailedsurfacescanner":         "Detailed Surface Scanner",
+	"dockingcomputer_standard":       "Standard Docking Computer",
+	"dockingcomputer_advanced":       "Advanced Docking Computer",
+	"supercruiseassist":              "Supercruise Assist",
+	"planetapproachsuite":            "Planetary Approach Suite",
+	"buggybay":                       "Planetary Vehicle Hangar",
+	"fighterbay":                     "Fighter Hangar",
+	"passengercabin":                 "Passenger Cabin",
+	"fsdinterdictor":                 "Frame Shift Drive Interdictor",
+	"guardianfsdbooster":             "Guardian FSD Booster",
+	"guardianhullreinforcement":      "Guardian Hull Reinforcement",
+	"guardianmodulereinforcement":    "Guardian Module Reinforcement",
+	"guardianshieldreinforcement":    "Guardian Shield Reinforcement",
+	"guardianpowerplant":             "Guardian Hybrid Power Plant",
+	"guardianpowerdistributor":       "Guardian Hybrid Power Distributor",
+	"dronecontrol_collection":        "Collector Limpet Controller",
+	"dronecontrol_prospector":        "Prospector Limpet Controller",
+	"dronecontrol_fueltransfer":      "Fuel Transfer Limpet Controller",
+	"dronecontrol_repair":            "Repair Limpet Controller",
+	"dronecontrol_resourcesiphon":    "Hatch Breaker Limpet Controller",
+	"dronecontrol_recon":             "Recon Limpet Controller",
+	"dronecontrol_decontamination":   "Decontamination Limpet Controller",
+	"dronecontrol_unkvesselresearch": "Research Limpet Controller",
+	"multidronecontrol_mining":       "Mining Multi Limpet Controller",
+	"multidronecontrol_operations":   "Operations Multi Limpet Controller",
+	"multidronecontrol_rescue":       "Rescue Multi Limpet Controller",
+	"multidronecontrol_xeno":         "Xeno Multi Limpet Controller",
+	"multidronecontrol_universal":    "Universal Multi Limpet Controller",
+}
+
+// shipNames maps ship symbols, as sent in shipyard messages and used as the
+// prefix of armour symbols, to their in game names.
+var shipNames = map[string]string{
+	"adder":                    "Adder",
+	"anaconda":                 "Anaconda",
+	"asp":                      "Asp Explorer",
+	"asp_scout":                "Asp Scout",
+	"belugaliner":              "Beluga Liner",
+	"cobramkiii":               "Cobra Mk III",
+	"cobramkiv":                "Cobra Mk IV",
+	"cobramkv":                 "Cobra Mk V",
+	"corsair":                  "Corsair",
+	"cutter":                   "Imperial Cutter",
+	"diamondback":              "Diamondback Scout",
+	"diamondbackxl":            "Diamondback Explorer",
+	"dolphin":                  "Dolphin",
+	"eagle":                    "Eagle",
+	"empire_courier":           "Imperial Courier",
+	"empire_eagle":             "Imperial Eagle",
+	"empire_trader":            "Imperial Clipper",
+	"federation_corvette":      "Federal Corvette",
+	"federation_dropship":      "Federal Dropship",
+	"federation_dropship_mkii": "Federal Assault Ship",
+	"federation_gunship":       "Federal Gunship",
+	"ferdelance":               "Fer-de-Lance",
+	"hauler":                   "Hauler",
+	"independant_trader":       "Keelback",
+	"krait_light":              "Krait Phantom",
+	"krait_mkii":               "Krait Mk II",
+	"mamba":                    "Mamba",
+	"mandalay":                 "Mandalay",
+	"orca":                     "Orca",
+	"panthermkii":              "Panther Clipper Mk II",
+	"python":                   "Python",
+	"python_nx":                "Python Mk II",
+	"sidewinder":               "Sidewinder",
+	"type6":                    "Type-6 Transporter",
+	"type7":                    "Type-7 Transporter",
+	"type8":                    "Type-8 Transporter",
+	"type9":                    "Type-9 Heavy",
+	"type9_military":           "Type-10 Defender",
+	"typex":                    "Alliance Chieftain",
+	"typex_2":                  "Alliance Crusader",
+	"typex_3":                  "Alliance Challenger",
+	"viper":                    "Viper Mk III",
+	"viper_mkiv":               "Viper Mk IV",
+	"vulture":                  "Vulture",
+}
+
+// ParseModule decodes an outfitting symbol such as
+// "Hpt_PulseLaser_Fixed_Small" or "Int_Hyperdrive_Size5_Class5". Symbols it
+// does not recognise still get a readable name built from their words.
+func ParseModule(symbol string) Module {
+	m := Module{Symbol: symbol}
+	words := strings.Split(strings.ToLower(symbol), "_")
+
+	switch words[0] {
+	case "hpt", "int":
+		words = words[1:]
+	default:
+		// Armour: <ship>_armour_<grade>
+		for i, w := range words {
+			if w == "armour" && i > 0 && i+1 < len(words) {
+				m.Ship = ShipName(strings.Join(words[:i], "_"))
+				m.Name = armour[words[i+1]]
+				if m.Name == "" {
+					m.Name = titleCase(words[i+1:]) + " Armour"
+				}
+				return m
+			}
+		}
+	}
+
+	// The name runs up to the first mount or size word; anything after
+	// that, such as "scatter" or "fast", is a variant the in game name
+	// already covers or that the table leaves out.
+	end := len(words)
+	for i, w := range words {
+		if mount, ok := mounts[w]; ok && i+1 < len(words) {
+			m.Mount = mount
+			m.Size = sizes[words[i+1]]
+			end = i
+			break
+		}
+		if n, ok := sizeWord(w); ok {
+			m.Class = n
+			if i+1 < len(words) {
+				m.Rating = ratings[words[i+1]]
+			}
+			end = i
+			break
+		}
+	}
+	m.Name = moduleName(words[:end])
+	return m
+}
+
+// ModuleName returns the in game name of an outfitting symbol.
+func ModuleName(symbol string) string {
+	return ParseModule(symbol).String()
+}
+
+// ShipName returns the in game name of a ship symbol.
+func ShipName(symbol string) string {
+	s := strings.ToLower(symbol)
+	if name, ok := shipNames[s]; ok {
+		return name
+	}
+	return titleCase(strings.Split(s, "_"))
+}
+
+// Resolve returns the symbols that query names, either the symbol itself
+// or its in game name as given by name, e.g. ModuleName. Both compare case
+// insensitively.
+func Resolve(query string, symbols []string, name func(string) string) []string {
+	var out []string
+	for _, sym := range symbols {
+		if strings.EqualFold(sym, query) || strings.EqualFold(name(sym), query) {
+			out = append(out, sym)
+		}
+	}
+	return out
+}
+
+func moduleName(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	if name, ok := moduleNames[strings.Join(words, "_")]; ok {
+		return name
+	}
+	return titleCase(words)
+}
+
+func sizeWord(w string) (int, bool) {
+	if !strings.HasPrefix(w, "size") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(w[len("size"):])
+	return n, err == nil
+}
+
+func titleCase(words []string) string {
+	out := make([]string, 0, len(words))
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		out = append(out, strings.ToUpper(w[:1])+w[1:])
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"EDDN/alerts"
+	"EDDN/bartender"
+	"EDDN/eddn"
+	"EDDN/galaxy"
+	"EDDN/sink"
+	"EDDN/stations"
+	"EDDN/store"
+	"EDDN/validate"
+	"flag"
+	"log"
+	"sort"
+	"time"
+)
+
+// outputs holds the flags shared by listen and replay that decide what
+// happens to decoded messages.
+type outputs struct {
+	db       *string
+	alerts   *string
+	sinks    *string
+	validate *bool
+	strict   *bool
+	tests    *bool
+	unknown  *time.Duration
+	dedup    *time.Duration
+	dedupMax *int
+
+	closers []func() error
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputs {
+	return &outputs{
+		db:       fs.String("db", "", "SQLite database to store markets, stations, outfitting, shipyards, carriers and docking requests in"),
+		alerts:   fs.String("alerts", "", "JSON file of alert rules, see alerts.example.json"),
+		sinks:    fs.String("sinks", "", "JSON file of output sinks, see sinks.example.json"),
+		validate: fs.Bool("validate", false, "check messages against the bundled EDDN JSON Schemas and log violations"),
+		strict:   fs.Bool("strict", false, "with -validate, drop messages that fail validation"),
+		tests:    fs.Bool("test", false, "log messages sent to /test schemas instead of discarding them"),
+		dedup:    fs.Duration("dedup", 0, "drop messages repeated within this window, e.g. 10m"),
+		dedupMax: fs.Int("dedup-max", 100000, "messages -dedup remembers at most"),
+		unknown:  fs.Duration("unknown-fields", 0, "every interval, log message fields Schemas.go does not declare that were seen during it"),
+	}
+}
+
+// configure applies the decode options to client.
+func (o *outputs) configure(client *eddn.Client) {
+	if *o.validate {
+		v, err := validate.New()
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.Validator = v
+		client.Strict = *o.strict
+	}
+
+	if *o.dedup > 0 {
+		d := eddn.NewDeduper(*o.dedup, *o.dedupMax)
+		client.Dedup = d
+		o.closers = append(o.closers, func() error {
+			logDuplicates(d.Stats())
+			return nil
+		})
+	}
+
+	if *o.tests {
+		tests := make(chan *eddn.Envelope)
+		client.Tests = tests
+		eddn.TestDispatcher.HandleAny(func(env *eddn.Envelope) {
+			log.Printf("Test message %s from %s %s\n", env.Schema, env.Header.SoftwareName, env.Header.SoftwareVersion)
+		})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			eddn.TestDispatcher.Serve(tests)
+		}()
+		o.closers = append(o.closers, func() error {
+			<-done
+			return nil
+		})
+	}
+}
+
+// attach registers handlers on the default dispatcher for every enabled output.
+func (o *outputs) attach() {
+	if *o.alerts != "" {
+		engine, err := alerts.Load(*o.alerts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		engine.Attach(eddn.DefaultDispatcher)
+		o.closers = append(o.closers, engine.Close)
+	}
+
+	if *o.sinks != "" {
+		set, err := sink.Load(*o.sinks)
+		if err != nil {
+			log.Fatal(err)
+		}
+		set.Attach(eddn.DefaultDispatcher)
+		o.closers = append(o.closers, set.Close)
+	}
+
+	if *o.unknown > 0 {
+		report := eddn.NewFieldReport(*o.unknown)
+		eddn.OnAny(report.Observe)
+		go func() {
+			for range time.Tick(*o.unknown) {
+				logUnknownFields(report)
+			}
+		}()
+		o.closers = append(o.closers, func() error {
+			logUnknownFields(report)
+			return nil
+		})
+	}
+
+	if *o.db != "" {
+		st, err := store.Open(*o.db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		o.closers = append(o.closers, st.Close)
+		eddn.On(func(h eddn.EDDNHeader, msg *eddn.CommodityMessage) {
+			if err := st.SaveCommodity(h, msg); err != nil {
+				log.Printf("Error storing market %s/%s: %v\n", msg.SystemName, msg.StationName, err)
+			}
+		})
+		eddn.On(func(h eddn.EDDNHeader, msg *eddn.FCMaterialsJournalMessage) {
+			if err := st.SaveCarrierSnapshot(bartender.FromMaterials(msg.Materials())); err != nil {
+				log.Printf("Error storing carrier %s: %v\n", msg.CarrierID, err)
+			}
+		})
+		eddn.On(func(h eddn.EDDNHeader, msg *eddn.FCMaterialsMessage) {
+			if err := st.SaveCarrierSnapshot(bartender.FromMaterials(msg.Materials())); err != nil {
+				log.Printf("Error storing carrier %s: %v\n", msg.CarrierID, err)
+			}
+		})
+		eddn.On(func(h eddn.EDDNHeader, msg *eddn.OutfittingMessage) {
+			if err := st.SaveOutfitting(msg); err != nil {
+				log.Printf("Error storing outfitting %s/%s: %v\n", msg.SystemName, msg.StationName, err)
+			}
+		})
+		eddn.On(func(h eddn.EDDNHeader, msg *eddn.ShipyardMessage) {
+			if err := st.SaveShipyard(msg); err != nil {
+				log.Printf("Error storing shipyard %s/%s: %v\n", msg.SystemName, msg.StationName, err)
+			}
+		})
+		eddn.On(func(h eddn.EDDNHeader, msg *eddn.DockingGrantedMessage) {
+			if err := st.SaveDockingGranted(msg); err != nil {
+				log.Printf("Error storing docking at %s: %v\n", msg.StationName, err)
+			}
+		})
+		eddn.On(func(h eddn.EDDNHeader, msg *eddn.DockingDeniedMessage) {
+			if err := st.SaveDockingDenied(msg); err != nil {
+				log.Printf("Error storing docking at %s: %v\n", msg.StationName, err)
+			}
+		})
+		// Each message only says part of what is known about a station; the
+		// directory merges them and the merged record is written back.
+		directory := stations.NewDirectory()
+		if err := st.LoadStations(directory); err != nil {
+			log.Fatal(err)
+		}
+		eddn.OnAny(func(env *eddn.Envelope) {
+			obs, at, ok := stations.Observe(env.Message)
+			if !ok {
+				return
+			}
+			merged, changed := directory.Observe(obs, at)
+			if !changed {
+				return
+			}
+			if err := st.SaveStation(merged); err != nil {
+				log.Printf("Error storing station %d: %v\n", merged.MarketID, err)
+			}
+		})
+		// The route planner and proximity queries need system positions.
+		// The catalogue remembers what has been written so repeat sightings
+		// of the same system do not hit the database.
+		seen := galaxy.NewCatalogue()
+		if err := st.LoadSystems(seen); err != nil {
+			log.Fatal(err)
+		}
+		eddn.OnAny(func(env *eddn.Envelope) {
+			for _, sys := range galaxy.Locate(env.Message) {
+				if !seen.Add(sys) {
+					continue
+				}
+				if err := st.SaveSystem(sys, env.Header.GatewayTimestamp); err != nil {
+					log.Printf("Error storing system %s: %v\n", sys.Name, err)
+				}
+			}
+		})
+	}
+}
+
+func logUnknownFields(report *eddn.FieldReport) {
+	for _, f := range report.Snapshot() {
+		if f.Example != nil {
+			log.Printf("Unknown field %s %s seen %d times, e.g. %.100s\n", f.Schema, f.Path, f.Count, f.Example)
+			continue
+		}
+		log.Printf("Unknown field %s %s seen %d times\n", f.Schema, f.Path, f.Count)
+	}
+}
+
+func logDuplicates(st eddn.DedupStats) {
+	for _, by := range []struct {
+		label  string
+		counts map[string]uint64
+	}{{"schema", st.BySchema}, {"software", st.BySoftware}} {
+		keys := make([]string, 0, len(by.counts))
+		for k := range by.counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			log.Printf("Suppressed %d duplicates by %s %s\n", by.counts[k], by.label, k)
+		}
+	}
+}
+
+func (o *outputs) close() {
+	for _, c := range o.closers {
+		if err := c(); err != nil {
+			log.Printf("%v\n", err)
+		}
+	}
+}
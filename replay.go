@@ -0,0 +1,58 @@
+package main
+
+import (
+	"EDDN/capture"
+	"EDDN/eddn"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+)
+
+func replay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	format := fs.String("format", "", "capture format, frames or jsonl (default: guessed from the file name)")
+	speed := fs.Float64("speed", 0, "replay speed relative to the recorded gateway timestamps; 1 is real time, 0 is as fast as possible")
+	out := addOutputFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatal("replay: no capture files given")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out.attach()
+	defer out.close()
+
+	frames := make(chan []byte)
+	go func() {
+		defer close(frames)
+		for _, path := range fs.Args() {
+			f := capture.FormatFor(path)
+			if *format != "" {
+				var err error
+				if f, err = capture.ParseFormat(*format); err != nil {
+					log.Fatal(err)
+				}
+			}
+			if err := capture.ReadFile(ctx, path, f, frames); err != nil {
+				log.Printf("Error reading capture %s: %v\n", path, err)
+			}
+		}
+	}()
+
+	// Replays keep the recorded order across all schemas so bugs reproduce
+	// deterministically and Pace sees gateway timestamps in sequence.
+	client := &eddn.Client{Ordered: true, OrderBy: eddn.OneSequence}
+	out.configure(client)
+	decoded := make(chan *eddn.Envelope)
+	go client.Consume(ctx, frames, decoded)
+
+	envelopes := make(chan *eddn.Envelope)
+	go capture.Pace(ctx, *speed, decoded, envelopes)
+
+	eddn.DefaultDispatcher.Serve(envelopes)
+}
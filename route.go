@@ -0,0 +1,81 @@
+package main
+
+import (
+	"EDDN/route"
+	"EDDN/store"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+func planRoute(args []string) {
+	fs := flag.NewFlagSet("route", flag.ExitOnError)
+	db := fs.String("db", "eddn.db", "SQLite database written by listen -db")
+	from := fs.String("from", "", "system to search around")
+	radius := fs.Float64("radius", 50, "only use markets within this many light years of -from")
+	capacity := fs.Int64("capacity", 100, "cargo capacity in tons")
+	jumpRange := fs.Float64("range", 20, "laden jump range in light years, only used to estimate jumps")
+	hops := fs.Int("hops", 3, "longest loop to search for")
+	maxAge := fs.Duration("max-age", 0, "ignore prices older than this")
+	pad := fs.String("pad", "", "required landing pad size: S, M or L")
+	noCarriers := fs.Bool("no-carriers", false, "leave fleet carriers out")
+	limit := fs.Int("limit", 10, "number of routes to show")
+	byTotal := fs.Bool("by-total", false, "rank by total profit instead of profit per ton")
+	asJSON := fs.Bool("json", false, "print routes as JSON")
+	fs.Parse(args)
+
+	if *from == "" {
+		log.Fatal("route: -from is required")
+	}
+
+	st, err := store.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	opt := route.Options{
+		Start:     *from,
+		Radius:    *radius,
+		Capacity:  *capacity,
+		JumpRange: *jumpRange,
+		MaxHops:   *hops,
+		Filter:    store.Filter{MaxAge: *maxAge, Pad: strings.ToUpper(*pad)},
+		Limit:     *limit,
+		ByTotal:   *byTotal,
+	}
+	if *noCarriers {
+		opt.Filter.Carriers = store.NoCarriers
+	}
+
+	routes, err := route.Plan(st, opt)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(routes)
+		return
+	}
+	if len(routes) == 0 {
+		fmt.Println("No profitable routes found.")
+		return
+	}
+	for i, r := range routes {
+		kind := "one-way"
+		if r.Loop {
+			kind = fmt.Sprintf("%d-hop loop", len(r.Legs))
+		}
+		fmt.Printf("%d. %s: %d cr/t, %d cr total, %.1f ly, ~%d jumps\n", i+1, kind, r.ProfitPerTon, r.Profit, r.Distance, r.Jumps)
+		for _, l := range r.Legs {
+			fmt.Printf("   %s / %s -> %s / %s: %d t %s, buy %d sell %d, +%d cr (%.1f ly)\n",
+				l.From.SystemName, l.From.StationName, l.To.SystemName, l.To.StationName,
+				l.Units, l.Commodity, l.BuyPrice, l.SellPrice, l.Profit, l.Distance)
+		}
+	}
+}
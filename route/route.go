@@ -0,0 +1,231 @@
+// Package route finds profitable trade runs between stored markets.
+package route
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"EDDN/galaxy"
+	"EDDN/store"
+)
+
+// Options describes the ship and the area to search.
+type Options struct {
+	// Start is the system the search is centred on.
+	Start string
+	// Radius limits every market on a route to this many light years from Start.
+	Radius float64
+	// Capacity is the cargo hold size in tons.
+	Capacity int64
+	// JumpRange is the ship's laden jump range. It only feeds the Jumps
+	// estimate of each leg and never rules a leg out, as any distance can be
+	// covered in enough jumps.
+	JumpRange float64
+	// MaxHops is the longest loop to consider. One-hop runs are always included.
+	MaxHops int
+	// Filter restricts which markets are used, e.g. by price age or pad size.
+	Filter store.Filter
+	// Limit caps the number of routes returned.
+	Limit int
+	// ByTotal ranks by total profit instead of profit per ton.
+	ByTotal bool
+}
+
+// Leg is a single buy-here, sell-there run.
+type Leg struct {
+	From      store.Market `json:"from"`
+	To        store.Market `json:"to"`
+	Commodity string       `json:"commodity"`
+	BuyPrice  int64        `json:"buyPrice"`
+	SellPrice int64        `json:"sellPrice"`
+	Units     int64        `json:"units"`
+	Profit    int64        `json:"profit"`
+	Distance  float64      `json:"distance"`
+	Jumps     int          `json:"jumps"`
+}
+
+// Route is a sequence of legs. Loop routes end where they started.
+// ProfitPerTon is the profit per ton actually carried, which is less than
+// a full hold on legs limited by stock or demand.
+type Route struct {
+	Legs         []Leg   `json:"legs"`
+	Loop         bool    `json:"loop"`
+	Profit       int64   `json:"profit"`
+	ProfitPerTon int64   `json:"profitPerTon"`
+	Distance     float64 `json:"distance"`
+	Jumps        int     `json:"jumps"`
+}
+
+// fanout bounds how many onward legs from each market the loop search
+// follows, which keeps multi-hop searches tractable around busy hubs.
+const fanout = 8
+
+// Plan loads the markets around opt.Start and returns the best routes.
+func Plan(st *store.Store, opt Options) ([]Route, error) {
+	if opt.Capacity <= 0 {
+		return nil, fmt.Errorf("cargo capacity must be positive")
+	}
+	start, err := st.SystemByName(opt.Start)
+	if store.IsNotFound(err) {
+		return nil, fmt.Errorf("no coordinates known for system %q", opt.Start)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	markets, err := st.MarketsWithin(start.Pos, opt.Radius, opt.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return Find(markets, opt), nil
+}
+
+// Find ranks routes between the given markets.
+func Find(markets []store.PlacedMarket, opt Options) []Route {
+	legs := bestLegs(markets, opt)
+
+	var routes []Route
+	for _, out := range legs {
+		for _, e := range out {
+			routes = append(routes, newRoute([]Leg{e.leg}, false))
+		}
+	}
+
+	for i := range markets {
+		searchLoops(legs, i, i, nil, make(map[int]bool), opt, &routes)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if opt.ByTotal && a.Profit != b.Profit {
+			return a.Profit > b.Profit
+		}
+		if a.ProfitPerTon != b.ProfitPerTon {
+			return a.ProfitPerTon > b.ProfitPerTon
+		}
+		if a.Profit != b.Profit {
+			return a.Profit > b.Profit
+		}
+		return a.Distance < b.Distance
+	})
+	if opt.Limit > 0 && len(routes) > opt.Limit {
+		routes = routes[:opt.Limit]
+	}
+	return routes
+}
+
+type edge struct {
+	to  int
+	leg Leg
+}
+
+// bestLegs finds, for every ordered pair of markets, the single most
+// profitable commodity to carry, keeping the top fanout pairs per origin.
+func bestLegs(markets []store.PlacedMarket, opt Options) [][]edge {
+	type sale struct{ price, demand int64 }
+	sells := make([]map[string]sale, len(markets))
+	for i, m := range markets {
+		sells[i] = make(map[string]sale)
+		for _, p := range m.Prices {
+			if p.SellPrice > 0 && p.Demand > 0 {
+				sells[i][p.Name] = sale{p.SellPrice, p.Demand}
+			}
+		}
+	}
+
+	legs := make([][]edge, len(markets))
+	for i, from := range markets {
+		var out []edge
+		for j, to := range markets {
+			if i == j {
+				continue
+			}
+			var best Leg
+			for _, p := range from.Prices {
+				if p.BuyPrice <= 0 || p.Stock <= 0 {
+					continue
+				}
+				s, ok := sells[j][p.Name]
+				if !ok || s.price <= p.BuyPrice {
+					continue
+				}
+				units := min64(opt.Capacity, p.Stock, s.demand)
+				profit := units * (s.price - p.BuyPrice)
+				if profit > best.Profit {
+					best = Leg{Commodity: p.Name, BuyPrice: p.BuyPrice, SellPrice: s.price, Units: units, Profit: profit}
+				}
+			}
+			if best.Profit == 0 {
+				continue
+			}
+			best.From, best.To = from.Market, to.Market
+			best.Distance = galaxy.Distance(from.Pos, to.Pos)
+			best.Jumps = jumps(best.Distance, opt.JumpRange)
+			out = append(out, edge{j, best})
+		}
+		sort.Slice(out, func(a, b int) bool { return out[a].leg.Profit > out[b].leg.Profit })
+		if len(out) > fanout {
+			out = out[:fanout]
+		}
+		legs[i] = out
+	}
+	return legs
+}
+
+// searchLoops extends path from market at through the best legs, recording
+// every path that returns to origin within opt.MaxHops legs. Each loop is
+// only recorded from its lowest-numbered market to avoid rotations.
+func searchLoops(legs [][]edge, origin, at int, path []Leg, seen map[int]bool, opt Options, routes *[]Route) {
+	if len(path) >= opt.MaxHops {
+		return
+	}
+	seen[at] = true
+	defer delete(seen, at)
+
+	for _, e := range legs[at] {
+		p := append(path[:len(path):len(path)], e.leg)
+		if e.to == origin {
+			if len(p) >= 2 {
+				*routes = append(*routes, newRoute(p, true))
+			}
+			continue
+		}
+		if e.to < origin || seen[e.to] {
+			continue
+		}
+		searchLoops(legs, origin, e.to, p, seen, opt, routes)
+	}
+}
+
+func newRoute(legs []Leg, loop bool) Route {
+	r := Route{Legs: legs, Loop: loop}
+	var units int64
+	for _, l := range legs {
+		r.Profit += l.Profit
+		r.Distance += l.Distance
+		r.Jumps += l.Jumps
+		units += l.Units
+	}
+	if units > 0 {
+		r.ProfitPerTon = r.Profit / units
+	}
+	return r
+}
+
+func jumps(distance, jumpRange float64) int {
+	if jumpRange <= 0 || distance == 0 {
+		return 0
+	}
+	return int(math.Ceil(distance / jumpRange))
+}
+
+func min64(v ...int64) int64 {
+	m := v[0]
+	for _, x := range v[1:] {
+		if x < m {
+			m = x
+		}
+	}
+	return m
+}
@@ -0,0 +1,160 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"EDDN/store"
+)
+
+// market returns a market at x on the x axis with the given prices, each
+// written as name:buy/stock:sell/demand with 0 for a missing side.
+func market(id int64, x float64, prices ...string) store.PlacedMarket {
+	m := store.PlacedMarket{
+		Market: store.Market{MarketID: id, StationName: fmt.Sprint("Station ", id)},
+		Pos:    [3]float64{x, 0, 0},
+	}
+	for _, p := range prices {
+		var p2 store.Price
+		var name string
+		fmt.Sscanf(strings.Replace(p, ":", " ", 1), "%s %d/%d:%d/%d", &name, &p2.BuyPrice, &p2.Stock, &p2.SellPrice, &p2.Demand)
+		p2.Name = name
+		m.Prices = append(m.Prices, p2)
+	}
+	return m
+}
+
+func TestLegUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int64
+		from, to string
+		units    int64
+	}{
+		{"capacity", 10, "gold:100/50:0/0", "gold:0/0:150/50", 10},
+		{"stock", 100, "gold:100/20:0/0", "gold:0/0:150/50", 20},
+		{"demand", 100, "gold:100/50:0/0", "gold:0/0:150/5", 5},
+	}
+	for _, tt := range tests {
+		routes := Find([]store.PlacedMarket{market(1, 0, tt.from), market(2, 10, tt.to)},
+			Options{Capacity: tt.capacity, JumpRange: 4, MaxHops: 3})
+		if len(routes) != 1 {
+			t.Fatalf("%s: %d routes, want 1", tt.name, len(routes))
+		}
+		r := routes[0]
+		l := r.Legs[0]
+		if l.Units != tt.units || l.Profit != tt.units*50 || r.ProfitPerTon != 50 {
+			t.Errorf("%s: %d units for %d cr, %d cr/t; want %d units at 50 cr/t", tt.name, l.Units, l.Profit, r.ProfitPerTon, tt.units)
+		}
+		if l.Jumps != 3 {
+			t.Errorf("%s: %d jumps for 10 ly at 4 ly, want 3", tt.name, l.Jumps)
+		}
+	}
+}
+
+func TestBestCommodity(t *testing.T) {
+	// Silver has the better margin but gold the better total.
+	routes := Find([]store.PlacedMarket{
+		market(1, 0, "gold:100/50:0/0", "silver:10/3:0/0", "tea:5/100:0/0"),
+		market(2, 10, "gold:0/0:150/50", "silver:0/0:100/50", "tea:0/0:4/100"),
+	}, Options{Capacity: 100, MaxHops: 2})
+	if len(routes) != 1 || routes[0].Legs[0].Commodity != "gold" {
+		t.Fatalf("routes %+v, want one gold run", routes)
+	}
+}
+
+func TestLoopProfitPerTon(t *testing.T) {
+	// Out with a full hold of gold, back with the four tons of tea the
+	// first market wants.
+	routes := Find([]store.PlacedMarket{
+		market(1, 0, "gold:100/50:0/0", "tea:0/0:200/4"),
+		market(2, 10, "gold:0/0:150/50", "tea:100/50:0/0"),
+	}, Options{Capacity: 10, MaxHops: 2})
+
+	var loop *Route
+	for i := range routes {
+		if routes[i].Loop {
+			loop = &routes[i]
+		}
+	}
+	if loop == nil {
+		t.Fatalf("no loop in %+v", routes)
+	}
+	if loop.Profit != 10*50+4*100 {
+		t.Errorf("loop profit %d, want 900", loop.Profit)
+	}
+	// 900 cr over the 14 tons carried, not over 20 tons of hold.
+	if loop.ProfitPerTon != 64 {
+		t.Errorf("loop profit per ton %d, want 64", loop.ProfitPerTon)
+	}
+	// The tea leg earns 100 cr/t and ranks first; the loop beats gold alone.
+	if routes[0].Legs[0].Commodity != "tea" || !routes[1].Loop {
+		t.Errorf("ranking %v", routes)
+	}
+}
+
+func TestLoopsFoundOnce(t *testing.T) {
+	// Every market sells something every other market buys.
+	var markets []store.PlacedMarket
+	for i := int64(1); i <= 4; i++ {
+		var prices []string
+		for j := int64(1); j <= 4; j++ {
+			if i == j {
+				prices = append(prices, fmt.Sprintf("c%d:100/100:0/0", j))
+			} else {
+				prices = append(prices, fmt.Sprintf("c%d:0/0:%d/100", j, 100+10*i))
+			}
+		}
+		markets = append(markets, market(i, float64(i), prices...))
+	}
+
+	tests := []struct {
+		hops, loops int
+	}{
+		// Pairs only: 6 unordered pairs.
+		{2, 6},
+		// Plus triangles: 4 sets of three, each in 2 directions.
+		{3, 6 + 8},
+		// Plus squares: 3 cycles through all four, in 2 directions.
+		{4, 6 + 8 + 6},
+	}
+	for _, tt := range tests {
+		routes := Find(markets, Options{Capacity: 10, MaxHops: tt.hops})
+		seen := make(map[string]bool)
+		loops, single := 0, 0
+		for _, r := range routes {
+			if !r.Loop {
+				single++
+				continue
+			}
+			loops++
+			key := cycleKey(r)
+			if seen[key] {
+				t.Errorf("hops %d: loop %s found twice", tt.hops, key)
+			}
+			seen[key] = true
+		}
+		if loops != tt.loops || single != 12 {
+			t.Errorf("hops %d: %d loops and %d one-way runs, want %d and 12", tt.hops, loops, single, tt.loops)
+		}
+	}
+}
+
+// cycleKey names a loop by its market sequence rotated to start at the
+// lowest market, so rotations of the same loop share a key.
+func cycleKey(r Route) string {
+	ids := make([]int64, len(r.Legs))
+	start := 0
+	for i, l := range r.Legs {
+		ids[i] = l.From.MarketID
+		if ids[i] < ids[start] {
+			start = i
+		}
+	}
+	var b strings.Builder
+	for i := range ids {
+		fmt.Fprint(&b, ids[(start+i)%len(ids)], " ")
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"EDDN/api"
+	"EDDN/galaxy"
+	"EDDN/store"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+)
+
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	db := fs.String("db", "eddn.db", "SQLite database written by listen -db")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	fs.Parse(args)
+
+	st, err := store.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	systems := galaxy.NewCatalogue()
+	if err := st.LoadSystems(systems); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Loaded %d systems\n", systems.Len())
+	// Pick up systems a concurrently running listen has written since.
+	go func() {
+		for range time.Tick(5 * time.Minute) {
+			if err := st.LoadSystems(systems); err != nil {
+				log.Printf("Error reloading systems: %v\n", err)
+			}
+		}
+	}()
+
+	log.Printf("Serving market data on http://%s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, api.NewServer(st, systems)))
+}
@@ -0,0 +1,110 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// Files writes JSON lines to one file per schema under Dir, named like
+// commodity-v3-20240501T100000.jsonl. A new file is started every UTC hour
+// and once the current one reaches MaxBytes. Lines are written unbuffered,
+// so files can be tailed and lose nothing on a crash.
+type Files struct {
+	Dir      string
+	MaxBytes int64
+
+	mu    sync.Mutex
+	files map[eddn.Schema]*openFile
+}
+
+type openFile struct {
+	f    *os.File
+	hour time.Time
+	size int64
+}
+
+func NewFiles(dir string, maxBytes int64) (*Files, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Files{Dir: dir, MaxBytes: maxBytes, files: make(map[eddn.Schema]*openFile)}, nil
+}
+
+func (fs *Files) Write(env *eddn.Envelope) error {
+	line, err := json.Marshal(NewRecord(env))
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	now := time.Now().UTC()
+	of := fs.files[env.Schema]
+	if of != nil && (!now.Truncate(time.Hour).Equal(of.hour) || (fs.MaxBytes > 0 && of.size >= fs.MaxBytes)) {
+		err := of.close()
+		delete(fs.files, env.Schema)
+		of = nil
+		if err != nil {
+			return err
+		}
+	}
+	if of == nil {
+		if of, err = fs.open(env.Schema, now); err != nil {
+			return err
+		}
+		fs.files[env.Schema] = of
+	}
+
+	n, err := of.f.Write(line)
+	of.size += int64(n)
+	return err
+}
+
+func (fs *Files) open(s eddn.Schema, now time.Time) (*openFile, error) {
+	prefix := fmt.Sprintf("%s-v%d", s.Family, s.Version)
+	if s.Test {
+		prefix += "-test"
+	}
+	stamp := now.Format("20060102T150405")
+	path := filepath.Join(fs.Dir, prefix+"-"+stamp+".jsonl")
+	// Several rotations can happen within a second when MaxBytes is small.
+	for i := 1; fileExists(path); i++ {
+		path = filepath.Join(fs.Dir, fmt.Sprintf("%s-%s-%d.jsonl", prefix, stamp, i))
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &openFile{f: f, hour: now.Truncate(time.Hour)}, nil
+}
+
+func (of *openFile) close() error {
+	return of.f.Close()
+}
+
+// Close closes every open file.
+func (fs *Files) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var first error
+	for s, of := range fs.files {
+		if err := of.close(); err != nil && first == nil {
+			first = err
+		}
+		delete(fs.files, s)
+	}
+	return first
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,175 @@
+// Package sink writes decoded messages to other tools: standard output,
+// rotating files and webhooks, chosen per schema by a config file.
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// Sink receives messages from the dispatch loop. Write must not block for
+// long; sinks that talk to the network queue and return.
+type Sink interface {
+	Write(env *eddn.Envelope) error
+	Close() error
+}
+
+// Record is what sinks write for each message: the EDDN document with the
+// message exactly as uploaded, plus when it was received if known.
+type Record struct {
+	SchemaRef string          `json:"$schemaRef"`
+	Header    eddn.EDDNHeader `json:"header"`
+	Message   json.RawMessage `json:"message"`
+	Received  *time.Time      `json:"receivedAt,omitempty"`
+}
+
+func NewRecord(env *eddn.Envelope) Record {
+	r := Record{SchemaRef: env.SchemaRef, Header: env.Header, Message: env.Raw}
+	if !env.Received.IsZero() {
+		received := env.Received
+		r.Received = &received
+	}
+	return r
+}
+
+// Config is the on-disk sink file.
+type Config struct {
+	Sinks []Spec `json:"sinks"`
+}
+
+// Spec configures one sink.
+//
+//	stdout  writes JSON lines to standard output
+//	file    writes JSON lines to Dir, one file per schema, rotated hourly
+//	        and after MaxMB megabytes
+//	webhook POSTs batches of up to Batch JSON lines to URL, at least every
+//	        Flush, retrying failed batches up to Retries times
+//
+// Schemas limits the sink to the listed schemas, given as a full
+// $schemaRef, family/version or just the family; empty means all.
+type Spec struct {
+	Type    string   `json:"type"`
+	Schemas []string `json:"schemas,omitempty"`
+
+	Dir   string `json:"dir,omitempty"`
+	MaxMB int64  `json:"maxMB,omitempty"`
+
+	URL     string `json:"url,omitempty"`
+	Batch   int    `json:"batch,omitempty"`
+	Flush   string `json:"flush,omitempty"`
+	Retries int    `json:"retries,omitempty"`
+}
+
+// Set is the configured sinks with their schema filters.
+type Set struct {
+	routes []route
+}
+
+type route struct {
+	name    string
+	schemas []string
+	sink    Sink
+}
+
+// Load reads a sink file and opens every sink in it.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	set := &Set{}
+	for i, spec := range cfg.Sinks {
+		s, err := spec.open()
+		if err != nil {
+			set.Close()
+			return nil, fmt.Errorf("%s: sink %d (%s): %v", path, i+1, spec.Type, err)
+		}
+		set.routes = append(set.routes, route{name: spec.Type, schemas: spec.Schemas, sink: s})
+	}
+	return set, nil
+}
+
+func (spec Spec) open() (Sink, error) {
+	switch spec.Type {
+	case "stdout":
+		return NewStdout(), nil
+	case "file":
+		if spec.Dir == "" {
+			return nil, fmt.Errorf("file sink needs a dir")
+		}
+		return NewFiles(spec.Dir, spec.MaxMB<<20)
+	case "webhook":
+		if spec.URL == "" {
+			return nil, fmt.Errorf("webhook sink needs a url")
+		}
+		w := NewWebhook(spec.URL)
+		if spec.Batch > 0 {
+			w.BatchSize = spec.Batch
+		}
+		if spec.Retries > 0 {
+			w.Retries = spec.Retries
+		}
+		if spec.Flush != "" {
+			d, err := time.ParseDuration(spec.Flush)
+			if err != nil {
+				return nil, fmt.Errorf("flush: %v", err)
+			}
+			w.FlushInterval = d
+		}
+		w.Start()
+		return w, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q", spec.Type)
+}
+
+// Attach fans every message on d out to the sinks whose schemas match.
+func (s *Set) Attach(d *eddn.Dispatcher) {
+	d.HandleAny(s.Write)
+}
+
+// Write sends env to every matching sink, logging failures.
+func (s *Set) Write(env *eddn.Envelope) {
+	for _, r := range s.routes {
+		if !matches(r.schemas, env.Schema) {
+			continue
+		}
+		if err := r.sink.Write(env); err != nil {
+			log.Printf("Error writing to %s sink: %v\n", r.name, err)
+		}
+	}
+}
+
+// Close closes every sink, flushing anything queued.
+func (s *Set) Close() error {
+	var first error
+	for _, r := range s.routes {
+		if err := r.sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func matches(patterns []string, schema eddn.Schema) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if p == schema.Ref() || strings.EqualFold(p, schema.Family) ||
+			strings.EqualFold(p, schema.Family+"/"+strconv.Itoa(schema.Version)) {
+			return true
+		}
+	}
+	return false
+}
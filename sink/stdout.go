@@ -0,0 +1,33 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"EDDN/eddn"
+)
+
+// Lines writes one JSON line per message to a writer.
+type Lines struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewLines(w io.Writer) *Lines {
+	return &Lines{enc: json.NewEncoder(w)}
+}
+
+// NewStdout writes JSON lines to standard output.
+func NewStdout() *Lines {
+	return NewLines(os.Stdout)
+}
+
+func (l *Lines) Write(env *eddn.Envelope) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(NewRecord(env))
+}
+
+func (l *Lines) Close() error { return nil }
@@ -0,0 +1,206 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// Webhook POSTs messages to URL in batches, as JSON lines with content type
+// application/x-ndjson. A batch is sent once it holds BatchSize messages or
+// FlushInterval after its first message, whichever comes first. Batches
+// are sent one at a time by a sender of their own, so batching carries on
+// while a failed batch is retried with exponential backoff; batches still
+// failing after Retries attempts are dropped and logged.
+type Webhook struct {
+	URL           string
+	BatchSize     int
+	FlushInterval time.Duration
+	Retries       int
+	// QueueSize bounds how many messages may wait; Write fails when full
+	// rather than hold up the dispatch loop.
+	QueueSize int
+	Client    *http.Client
+
+	queue   chan []byte
+	batches chan batch
+	done    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	dropped uint64
+}
+
+// pendingBatches bounds how many full batches may wait for the sender.
+// Further batches are dropped until it catches up.
+const pendingBatches = 8
+
+type batch struct {
+	body []byte
+	n    int
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		URL:           url,
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+		Retries:       5,
+		QueueSize:     10000,
+		Client:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Start begins sending. Settings must not change afterwards.
+func (w *Webhook) Start() {
+	w.queue = make(chan []byte, w.QueueSize)
+	w.batches = make(chan batch, pendingBatches)
+	w.done = make(chan struct{})
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	go w.run()
+	go w.sender()
+}
+
+func (w *Webhook) Write(env *eddn.Envelope) error {
+	line, err := json.Marshal(NewRecord(env))
+	if err != nil {
+		return err
+	}
+	select {
+	case w.queue <- line:
+		return nil
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+		return fmt.Errorf("webhook %s: queue full, message dropped", w.URL)
+	}
+}
+
+// Dropped returns how many messages were discarded because the queue, or
+// the batches waiting for the sender, were full.
+func (w *Webhook) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close sends whatever is queued and stops, abandoning batches still
+// failing after a minute.
+func (w *Webhook) Close() error {
+	close(w.queue)
+	select {
+	case <-w.done:
+	case <-time.After(time.Minute):
+		w.cancel()
+		<-w.done
+	}
+	w.cancel()
+	return nil
+}
+
+// run gathers queued messages into batches for the sender.
+func (w *Webhook) run() {
+	defer close(w.batches)
+
+	var buf bytes.Buffer
+	n := 0
+	timer := time.NewTimer(w.FlushInterval)
+	timer.Stop()
+
+	flush := func() {
+		if n > 0 {
+			b := batch{body: append([]byte(nil), buf.Bytes()...), n: n}
+			select {
+			case w.batches <- b:
+			default:
+				atomic.AddUint64(&w.dropped, uint64(n))
+				log.Printf("Error sending %d messages to %s: sender backlogged, batch dropped\n", n, w.URL)
+			}
+		}
+		buf.Reset()
+		n = 0
+	}
+
+	for {
+		select {
+		case line, ok := <-w.queue:
+			if !ok {
+				if n > 0 {
+					w.batches <- batch{body: buf.Bytes(), n: n}
+				}
+				return
+			}
+			if n == 0 {
+				timer.Reset(w.FlushInterval)
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+			n++
+			if n >= w.BatchSize {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// sender posts batches one at a time until run has no more.
+func (w *Webhook) sender() {
+	defer close(w.done)
+	for b := range w.batches {
+		w.send(b.body, b.n)
+	}
+}
+
+func (w *Webhook) send(body []byte, n int) {
+	delay := time.Second
+	for attempt := 0; ; attempt++ {
+		err := w.post(body)
+		if err == nil {
+			return
+		}
+		if attempt >= w.Retries {
+			log.Printf("Error sending %d messages to %s, giving up: %v\n", n, w.URL, err)
+			return
+		}
+		log.Printf("Error sending %d messages to %s, retrying in %s: %v\n", n, w.URL, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-w.ctx.Done():
+			log.Printf("Dropping %d messages for %s on shutdown\n", n, w.URL)
+			return
+		}
+		if delay < time.Minute {
+			delay *= 2
+		}
+	}
+}
+
+func (w *Webhook) post(body []byte) error {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("answered %s", resp.Status)
+	}
+	return nil
+}
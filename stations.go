@@ -0,0 +1,73 @@
+package main
+
+import (
+	"EDDN/stations"
+	"EDDN/store"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+func listStations(args []string) {
+	fs := flag.NewFlagSet("stations", flag.ExitOnError)
+	db := fs.String("db", "eddn.db", "SQLite database written by listen -db")
+	system := fs.String("system", "", "system to list the stations of")
+	service := fs.String("service", "", "only stations offering this service, e.g. \"Material Trader\" or \"Interstellar Factors\"")
+	large := fs.Bool("large", false, "only stations with large landing pads")
+	asJSON := fs.Bool("json", false, "print the merged records as JSON, with when each field was last updated")
+	fs.Parse(args)
+
+	if *system == "" {
+		log.Fatal("stations: -system is required")
+	}
+
+	st, err := store.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	list, err := st.StationsInSystem(*system, stations.Query{Service: *service, LargePads: *large})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(list)
+		return
+	}
+	if len(list) == 0 {
+		fmt.Println("No stations found.")
+		return
+	}
+	for _, s := range list {
+		name := s.Name
+		if name == "" {
+			name = fmt.Sprintf("market %d", s.MarketID)
+		}
+		fmt.Printf("%s (%s)", name, orUnknown(s.Type))
+		if s.LandingPads != nil {
+			fmt.Printf(", pads S%d M%d L%d", s.LandingPads.Small, s.LandingPads.Medium, s.LandingPads.Large)
+		}
+		if s.DistFromStarLS > 0 {
+			fmt.Printf(", %.0f ls", s.DistFromStarLS)
+		}
+		fmt.Println()
+		if len(s.Services) > 0 {
+			fmt.Printf("   services: %s (as of %s)\n", strings.Join(s.Services, ", "), s.Updated["services"].UTC().Format(time.RFC3339))
+		}
+	}
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown type"
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package stations
+
+import (
+	"sync"
+	"time"
+)
+
+// Directory holds the merged record of every station seen. It is safe for
+// concurrent use.
+type Directory struct {
+	mu       sync.Mutex
+	stations map[int64]*Station
+}
+
+func NewDirectory() *Directory {
+	return &Directory{stations: make(map[int64]*Station)}
+}
+
+// Add stores a complete record, such as one loaded from the database,
+// replacing any held for its MarketID.
+func (d *Directory) Add(s Station) {
+	if s.MarketID == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stations[s.MarketID] = &s
+}
+
+// Observe merges what one message reported about a station, observed at
+// at. It returns the merged record and whether anything changed.
+func (d *Directory) Observe(obs Station, at time.Time) (Station, bool) {
+	if obs.MarketID == 0 {
+		return Station{}, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.stations[obs.MarketID]
+	if !ok {
+		s = &Station{MarketID: obs.MarketID}
+		d.stations[obs.MarketID] = s
+	}
+	changed := s.Merge(obs, at)
+	return d.copy(s), changed
+}
+
+// Get returns the record for a MarketID.
+func (d *Directory) Get(marketID int64) (Station, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.stations[marketID]
+	if !ok {
+		return Station{}, false
+	}
+	return d.copy(s), true
+}
+
+func (d *Directory) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.stations)
+}
+
+// copy returns a copy of s safe to use after the lock is released. Merge
+// replaces slices and pointers rather than modifying them, so only the
+// Updated map needs copying.
+func (d *Directory) copy(s *Station) Station {
+	c := *s
+	c.Updated = make(map[string]time.Time, len(s.Updated))
+	for k, v := range s.Updated {
+		c.Updated[k] = v
+	}
+	return c
+}
+
+// Query selects stations. The zero value matches every station.
+type Query struct {
+	// Service must be among the station's services, e.g. "Material
+	// Trader" or "facilitator".
+	Service string
+	// LargePads requires somewhere large ships can dock.
+	LargePads bool
+}
+
+// Matches reports whether s satisfies q.
+func (q Query) Matches(s *Station) bool {
+	if q.Service != "" && !s.HasService(q.Service) {
+		return false
+	}
+	if q.LargePads && !s.HasLargePads() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,296 @@
+// Package stations merges the station details scattered across EDDN
+// schemas into one record per MarketID.
+package stations
+
+import (
+	"strings"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// Station is everything known about a station. Each message only reports
+// some fields, so Updated records when each field, by its JSON name, was
+// last reported; a field missing from Updated has never been seen.
+type Station struct {
+	MarketID             int64                 `json:"marketId"`
+	Name                 string                `json:"name,omitempty"`
+	SystemName           string                `json:"systemName,omitempty"`
+	SystemAddress        int64                 `json:"systemAddress,omitempty"`
+	Type                 string                `json:"type,omitempty"`
+	CarrierDockingAccess string                `json:"carrierDockingAccess,omitempty"`
+	Government           string                `json:"government,omitempty"`
+	Allegiance           string                `json:"allegiance,omitempty"`
+	Economy              string                `json:"economy,omitempty"`
+	Economies            []eddn.StationEconomy `json:"economies,omitempty"`
+	Faction              *eddn.StationFaction  `json:"faction,omitempty"`
+	Services             []string              `json:"services,omitempty"`
+	LandingPads          *eddn.LandingPads     `json:"landingPads,omitempty"`
+	DistFromStarLS       float64               `json:"distFromStarLS,omitempty"`
+	Body                 string                `json:"body,omitempty"`
+	Latitude             *float64              `json:"latitude,omitempty"`
+	Longitude            *float64              `json:"longitude,omitempty"`
+
+	Updated map[string]time.Time `json:"updated"`
+}
+
+// field copies one field from src to dst if src has it, reporting whether
+// it did.
+type field struct {
+	name string
+	copy func(dst, src *Station) bool
+}
+
+func str(get func(*Station) *string) func(dst, src *Station) bool {
+	return func(dst, src *Station) bool {
+		if *get(src) == "" {
+			return false
+		}
+		*get(dst) = *get(src)
+		return true
+	}
+}
+
+var fields = []field{
+	{"name", str(func(s *Station) *string { return &s.Name })},
+	{"systemName", str(func(s *Station) *string { return &s.SystemName })},
+	{"systemAddress", func(dst, src *Station) bool {
+		if src.SystemAddress == 0 {
+			return false
+		}
+		dst.SystemAddress = src.SystemAddress
+		return true
+	}},
+	{"type", str(func(s *Station) *string { return &s.Type })},
+	{"carrierDockingAccess", str(func(s *Station) *string { return &s.CarrierDockingAccess })},
+	{"government", str(func(s *Station) *string { return &s.Government })},
+	{"allegiance", str(func(s *Station) *string { return &s.Allegiance })},
+	{"economy", str(func(s *Station) *string { return &s.Economy })},
+	{"economies", func(dst, src *Station) bool {
+		if src.Economies == nil {
+			return false
+		}
+		dst.Economies = src.Economies
+		return true
+	}},
+	{"faction", func(dst, src *Station) bool {
+		if src.Faction == nil {
+			return false
+		}
+		dst.Faction = src.Faction
+		return true
+	}},
+	{"services", func(dst, src *Station) bool {
+		if src.Services == nil {
+			return false
+		}
+		dst.Services = src.Services
+		return true
+	}},
+	{"landingPads", func(dst, src *Station) bool {
+		if src.LandingPads == nil {
+			return false
+		}
+		dst.LandingPads = src.LandingPads
+		return true
+	}},
+	{"distFromStarLS", func(dst, src *Station) bool {
+		if src.DistFromStarLS == 0 {
+			return false
+		}
+		dst.DistFromStarLS = src.DistFromStarLS
+		return true
+	}},
+	{"body", str(func(s *Station) *string { return &s.Body })},
+	{"latitude", func(dst, src *Station) bool {
+		if src.Latitude == nil {
+			return false
+		}
+		dst.Latitude = src.Latitude
+		return true
+	}},
+	{"longitude", func(dst, src *Station) bool {
+		if src.Longitude == nil {
+			return false
+		}
+		dst.Longitude = src.Longitude
+		return true
+	}},
+}
+
+// Merge copies every field obs reports that is at least as new as what s
+// already has, and reports whether s changed.
+func (s *Station) Merge(obs Station, at time.Time) bool {
+	if s.Updated == nil {
+		s.Updated = make(map[string]time.Time)
+	}
+	if s.MarketID == 0 {
+		s.MarketID = obs.MarketID
+	}
+	changed := false
+	for _, f := range fields {
+		if last, ok := s.Updated[f.name]; ok && at.Before(last) {
+			continue
+		}
+		if !f.copy(s, &obs) {
+			continue
+		}
+		s.Updated[f.name] = at
+		changed = true
+	}
+	return changed
+}
+
+// Observe extracts what a decoded message says about a station. It reports
+// false for messages about no particular station.
+func Observe(msg interface{}) (Station, time.Time, bool) {
+	switch v := msg.(type) {
+	case *eddn.CommodityMessage:
+		return Station{
+			MarketID:             int64(v.MarketID),
+			Name:                 v.StationName,
+			SystemName:           v.SystemName,
+			Type:                 v.StationType,
+			CarrierDockingAccess: v.CarrierDockingAccess,
+		}, v.Timestamp.Time, v.MarketID != 0
+	case *eddn.ApproachSettlementMessage:
+		lat, long := v.Latitude, v.Longitude
+		s := Station{
+			MarketID:      int64(v.MarketID),
+			Name:          v.Name,
+			SystemName:    v.StarSystem,
+			SystemAddress: int64(v.SystemAddress),
+			Economies:     v.StationEconomies,
+			Faction:       v.StationFaction,
+			Services:      v.StationServices,
+			Body:          v.BodyName,
+			Latitude:      &lat,
+			Longitude:     &long,
+		}
+		s.Government = deref(v.StationGovernment)
+		s.Allegiance = deref(v.StationAllegiance)
+		s.Economy = deref(v.StationEconomy)
+		return s, v.Timestamp.Time, v.MarketID != 0
+	case *eddn.DockingGrantedMessage:
+		return Station{MarketID: int64(v.MarketID), Name: v.StationName, Type: v.StationType},
+			v.Timestamp.Time, v.MarketID != 0
+	case *eddn.DockingDeniedMessage:
+		return Station{MarketID: int64(v.MarketID), Name: v.StationName, Type: v.StationType},
+			v.Timestamp.Time, v.MarketID != 0
+	case *eddn.OutfittingMessage:
+		return Station{MarketID: int64(v.MarketID), Name: v.StationName, SystemName: v.SystemName},
+			v.Timestamp.Time, v.MarketID != 0
+	case *eddn.ShipyardMessage:
+		return Station{MarketID: int64(v.MarketID), Name: v.StationName, SystemName: v.SystemName},
+			v.Timestamp.Time, v.MarketID != 0
+	case *eddn.JournalMessage:
+		var info *eddn.StationInfo
+		switch e := v.Detail.(type) {
+		case *eddn.DockedEvent:
+			info = &e.StationInfo
+		case *eddn.LocationEvent:
+			if e.Docked {
+				info = &e.StationInfo
+			}
+		case *eddn.CarrierJumpEvent:
+			if e.Docked {
+				info = &e.StationInfo
+			}
+		}
+		if info == nil || info.MarketID == 0 {
+			return Station{}, time.Time{}, false
+		}
+		return Station{
+			MarketID:       int64(info.MarketID),
+			Name:           info.StationName,
+			SystemName:     v.StarSystem,
+			SystemAddress:  int64(v.SystemAddress),
+			Type:           info.StationType,
+			Government:     info.StationGovernment,
+			Allegiance:     info.StationAllegiance,
+			Economy:        info.StationEconomy,
+			Economies:      info.StationEconomies,
+			Faction:        info.StationFaction,
+			Services:       info.StationServices,
+			LandingPads:    info.LandingPads,
+			DistFromStarLS: info.DistFromStarLS,
+		}, v.Timestamp.Time, true
+	}
+	return Station{}, time.Time{}, false
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// serviceAliases maps the names players use to the StationServices values
+// the journal writes.
+var serviceAliases = map[string]string{
+	"materialtrader":             "materialtrader",
+	"interstellarfactors":        "facilitator",
+	"technologybroker":           "techbroker",
+	"universalcartographics":     "exploration",
+	"blackmarket":                "blackmarket",
+	"market":                     "commodities",
+	"commoditymarket":            "commodities",
+	"refuel":                     "refuel",
+	"repair":                     "repair",
+	"restock":                    "rearm",
+	"shipyard":                   "shipyard",
+	"outfitting":                 "outfitting",
+	"missions":                   "missions",
+	"searchandrescue":            "searchrescue",
+	"pioneersupplies":            "shop",
+	"vistagenomics":              "vistagenomics",
+	"bartender":                  "bar",
+	"fleetcarrieradministration": "carriermanagement",
+	"frontlinesolutions":         "frontlinesolutions",
+	"crewlounge":                 "crewlounge",
+	"powerplay":                  "powercontact",
+}
+
+// serviceKey reduces a service name or alias to the journal's spelling,
+// lower cased.
+func serviceKey(name string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(name), ""))
+	if s, ok := serviceAliases[key]; ok {
+		return s
+	}
+	return key
+}
+
+// HasService reports whether the station's last known services include
+// service, given as the journal spells it ("facilitator") or as the game
+// shows it ("Interstellar Factors").
+func (s *Station) HasService(service string) bool {
+	key := serviceKey(service)
+	for _, have := range s.Services {
+		if strings.ToLower(have) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// NoLargePadTypes are the station types that have no large pads.
+var NoLargePadTypes = []string{"Outpost", "OnFootSettlement"}
+
+// HasLargePads reports whether large ships can dock. Without a pad count
+// it goes by type; it reports false when neither is known.
+func (s *Station) HasLargePads() bool {
+	if s.LandingPads != nil {
+		return s.LandingPads.Large > 0
+	}
+	if s.Type == "" {
+		return false
+	}
+	for _, t := range NoLargePadTypes {
+		if s.Type == t {
+			return false
+		}
+	}
+	return true
+}
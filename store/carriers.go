@@ -0,0 +1,203 @@
+package store
+
+import (
+	"time"
+
+	"EDDN/bartender"
+)
+
+// Order sides as stored in carrier_orders.side.
+const (
+	Sell = "sell"
+	Buy  = "buy"
+)
+
+// SaveCarrierSnapshot records a carrier bartender's orders. Every order is
+// appended to carrier_order_history; the current orders are only replaced
+// when the snapshot is at least as new as the carrier row, and only for the
+// sides the snapshot reports.
+func (s *Store) SaveCarrierSnapshot(snap bartender.Snapshot) error {
+	if snap.CarrierID == "" {
+		return nil
+	}
+	ts := snap.Timestamp.UTC().Format(time.RFC3339)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO carriers (carrier_id, name, market_id, timestamp)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (carrier_id) DO UPDATE SET
+			name = CASE WHEN excluded.name = '' THEN carriers.name ELSE excluded.name END,
+			market_id = CASE WHEN excluded.market_id = 0 THEN carriers.market_id ELSE excluded.market_id END,
+			timestamp = excluded.timestamp
+		WHERE excluded.timestamp >= carriers.timestamp`,
+		snap.CarrierID, snap.CarrierName, snap.MarketID, ts)
+	if err != nil {
+		return err
+	}
+	latest, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	history, err := tx.Prepare(`
+		INSERT INTO carrier_order_history (carrier_id, side, name, count, price, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	sides := []struct {
+		side   string
+		known  bool
+		orders map[string]bartender.Mat
+	}{{Sell, snap.SellsKnown, snap.Sells}, {Buy, snap.BuysKnown, snap.Buys}}
+
+	for _, sd := range sides {
+		for name, m := range sd.orders {
+			if _, err := history.Exec(snap.CarrierID, sd.side, name, m.Count, m.Price, ts); err != nil {
+				return err
+			}
+		}
+	}
+
+	// An older snapshot arriving late goes into history only.
+	if latest == 0 {
+		return tx.Commit()
+	}
+
+	current, err := tx.Prepare(`
+		INSERT INTO carrier_orders (carrier_id, side, name, count, price, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer current.Close()
+
+	for _, sd := range sides {
+		if !sd.known {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM carrier_orders WHERE carrier_id = ? AND side = ?`, snap.CarrierID, sd.side); err != nil {
+			return err
+		}
+		for name, m := range sd.orders {
+			if _, err := current.Exec(snap.CarrierID, sd.side, name, m.Count, m.Price, ts); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadCarriers adds every stored carrier and its current orders to t.
+func (s *Store) LoadCarriers(t *bartender.Tracker) error {
+	snaps := make(map[string]*bartender.Snapshot)
+	rows, err := s.db.Query(`SELECT carrier_id, name, market_id, timestamp FROM carriers`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			snap bartender.Snapshot
+			ts   string
+		)
+		if err := rows.Scan(&snap.CarrierID, &snap.CarrierName, &snap.MarketID, &ts); err != nil {
+			return err
+		}
+		snap.Timestamp, _ = time.Parse(time.RFC3339, ts)
+		snap.Sells = make(map[string]bartender.Mat)
+		snap.Buys = make(map[string]bartender.Mat)
+		snap.SellsKnown, snap.BuysKnown = true, true
+		snaps[snap.CarrierID] = &snap
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	orders, err := s.db.Query(`SELECT carrier_id, side, name, count, price FROM carrier_orders`)
+	if err != nil {
+		return err
+	}
+	defer orders.Close()
+
+	for orders.Next() {
+		var (
+			id, side, name string
+			m              bartender.Mat
+		)
+		if err := orders.Scan(&id, &side, &name, &m.Count, &m.Price); err != nil {
+			return err
+		}
+		snap, ok := snaps[id]
+		if !ok {
+			continue
+		}
+		if side == Sell {
+			snap.Sells[name] = m
+		} else {
+			snap.Buys[name] = m
+		}
+	}
+	if err := orders.Err(); err != nil {
+		return err
+	}
+
+	for _, snap := range snaps {
+		t.Apply(*snap)
+	}
+	return nil
+}
+
+// CarrierOrder is one historical order of a carrier bartender.
+type CarrierOrder struct {
+	Side      string    `json:"side"`
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	Price     int       `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CarrierHistory returns the recorded orders of a carrier, newest first,
+// optionally limited to one material.
+func (s *Store) CarrierHistory(carrierID, material string, limit int) ([]CarrierOrder, error) {
+	query := `SELECT side, name, count, price, timestamp FROM carrier_order_history WHERE carrier_id = ?`
+	args := []interface{}{carrierID}
+	if material != "" {
+		query += ` AND name = ?`
+		args = append(args, bartender.Normalize(material))
+	}
+	query += ` ORDER BY timestamp DESC, id DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CarrierOrder
+	for rows.Next() {
+		var (
+			o  CarrierOrder
+			ts string
+		)
+		if err := rows.Scan(&o.Side, &o.Name, &o.Count, &o.Price, &ts); err != nil {
+			return nil, err
+		}
+		o.Timestamp, _ = time.Parse(time.RFC3339, ts)
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
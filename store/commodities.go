@@ -0,0 +1,103 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// SaveCommodity records a commodity market snapshot. Every entry is appended
+// to commodity_history; the market row and its current prices are only
+// replaced when the snapshot is at least as new as the one already stored.
+func (s *Store) SaveCommodity(header eddn.EDDNHeader, msg *eddn.CommodityMessage) error {
+	economies := []eddn.Economy{}
+	economies = append(economies, msg.Economies...)
+	economiesJSON, err := json.Marshal(economies)
+	if err != nil {
+		return err
+	}
+	prohibited := []string{}
+	prohibited = append(prohibited, msg.Prohibited...)
+	prohibitedJSON, err := json.Marshal(prohibited)
+	if err != nil {
+		return err
+	}
+	marketID := int64(msg.MarketID)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO markets (market_id, station_name, system_name, station_type, carrier_docking_access,
+			economies, prohibited, horizons, odyssey, timestamp, gateway_timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (market_id) DO UPDATE SET
+			station_name = excluded.station_name,
+			system_name = excluded.system_name,
+			station_type = excluded.station_type,
+			carrier_docking_access = excluded.carrier_docking_access,
+			economies = excluded.economies,
+			prohibited = excluded.prohibited,
+			horizons = excluded.horizons,
+			odyssey = excluded.odyssey,
+			timestamp = excluded.timestamp,
+			gateway_timestamp = excluded.gateway_timestamp
+		WHERE excluded.timestamp >= markets.timestamp`,
+		marketID, msg.StationName, msg.SystemName, msg.StationType, msg.CarrierDockingAccess,
+		string(economiesJSON), string(prohibitedJSON), msg.Horizons, msg.Odyssey,
+		msg.Timestamp, header.GatewayTimestamp.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	latest, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	history, err := tx.Prepare(`
+		INSERT INTO commodity_history (market_id, name, mean_price, buy_price, stock, stock_bracket,
+			sell_price, demand, demand_bracket, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	for _, c := range msg.Commodities {
+		if _, err := history.Exec(marketID, c.Name, int64(c.MeanPrice), int64(c.BuyPrice), int64(c.Stock),
+			int64(c.StockBracket), int64(c.SellPrice), int64(c.Demand), int64(c.DemandBracket), msg.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	// An older snapshot arriving late goes into history only.
+	if latest == 0 {
+		return tx.Commit()
+	}
+
+	if _, err := tx.Exec(`DELETE FROM market_commodities WHERE market_id = ?`, marketID); err != nil {
+		return err
+	}
+	current, err := tx.Prepare(`
+		INSERT INTO market_commodities (market_id, name, mean_price, buy_price, stock, stock_bracket,
+			sell_price, demand, demand_bracket, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (market_id, name) DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer current.Close()
+
+	for _, c := range msg.Commodities {
+		if _, err := current.Exec(marketID, c.Name, int64(c.MeanPrice), int64(c.BuyPrice), int64(c.Stock),
+			int64(c.StockBracket), int64(c.SellPrice), int64(c.Demand), int64(c.DemandBracket), msg.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,248 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"EDDN/eddn"
+)
+
+// SaveDockingGranted records a granted docking request.
+func (s *Store) SaveDockingGranted(msg *eddn.DockingGrantedMessage) error {
+	return s.saveDocking(int64(msg.MarketID), msg.StationName, msg.StationType, true, msg.LandingPad, "", msg.Timestamp)
+}
+
+// SaveDockingDenied records a denied docking request.
+func (s *Store) SaveDockingDenied(msg *eddn.DockingDeniedMessage) error {
+	return s.saveDocking(int64(msg.MarketID), msg.StationName, msg.StationType, false, 0, msg.Reason, msg.Timestamp)
+}
+
+func (s *Store) saveDocking(marketID int64, station, stationType string, granted bool, pad int, reason string, ts eddn.Time) error {
+	if marketID == 0 {
+		return nil
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO docking_events (market_id, station_name, station_type, granted, landing_pad, reason, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		marketID, station, stationType, granted, pad, reason, ts)
+	return err
+}
+
+// DockingStats summarises the docking requests seen at one station.
+type DockingStats struct {
+	MarketID    int64  `json:"marketId"`
+	StationName string `json:"stationName"`
+	StationType string `json:"stationType,omitempty"`
+	Granted     int    `json:"granted"`
+	Denied      int    `json:"denied"`
+	// Reasons counts denials by Reason, e.g. NoSpace or Hostile.
+	Reasons map[string]int `json:"reasons"`
+	// Pads counts grants by landing pad number.
+	Pads map[int]int `json:"pads,omitempty"`
+	// Traffic is the number of requests per interval, oldest first. Only
+	// DockingHistory fills it.
+	Traffic []TrafficBucket `json:"traffic,omitempty"`
+}
+
+// Requests is the total number of docking requests.
+func (d *DockingStats) Requests() int {
+	return d.Granted + d.Denied
+}
+
+// DenialRate is the share of requests denied, 0 to 1.
+func (d *DockingStats) DenialRate() float64 {
+	if d.Requests() == 0 {
+		return 0
+	}
+	return float64(d.Denied) / float64(d.Requests())
+}
+
+// TrafficBucket counts requests in the interval starting at Start.
+type TrafficBucket struct {
+	Start   time.Time `json:"start"`
+	Granted int       `json:"granted"`
+	Denied  int       `json:"denied"`
+}
+
+// DockingOrder ranks stations in BusiestStations.
+type DockingOrder int
+
+const (
+	// ByTraffic ranks by number of requests.
+	ByTraffic DockingOrder = iota
+	// ByDenialRate ranks by the share of requests denied.
+	ByDenialRate
+	// ByHostile ranks by denials with Reason Hostile.
+	ByHostile
+)
+
+// DockingFilter narrows BusiestStations.
+type DockingFilter struct {
+	// Since drops requests before this time.
+	Since time.Time
+	// MinRequests drops stations with fewer requests, so a single denial
+	// does not top the denial rate ranking.
+	MinRequests int
+	// Carriers selects fleet carriers or other stations.
+	Carriers Carriers
+}
+
+// BusiestStations ranks the stations with docking requests since f.Since.
+func (s *Store) BusiestStations(f DockingFilter, order DockingOrder, limit int) ([]DockingStats, error) {
+	var b strings.Builder
+	b.WriteString(`SELECT market_id, MAX(station_name), MAX(station_type), SUM(granted), SUM(1 - granted)
+		FROM docking_events WHERE timestamp >= ?`)
+	args := []interface{}{f.Since.UTC().Format(time.RFC3339)}
+	switch f.Carriers {
+	case OnlyCarriers:
+		b.WriteString(" AND station_type = ?")
+		args = append(args, carrierType)
+	case NoCarriers:
+		b.WriteString(" AND station_type != ?")
+		args = append(args, carrierType)
+	}
+	b.WriteString(" GROUP BY market_id HAVING COUNT(*) >= ?")
+	args = append(args, f.MinRequests)
+
+	rows, err := s.db.Query(b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DockingStats
+	index := make(map[int64]int)
+	for rows.Next() {
+		var d DockingStats
+		if err := rows.Scan(&d.MarketID, &d.StationName, &d.StationType, &d.Granted, &d.Denied); err != nil {
+			return nil, err
+		}
+		d.Reasons = make(map[string]int)
+		d.Pads = make(map[int]int)
+		index[d.MarketID] = len(out)
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.dockingBreakdown(f.Since, 0, index, out); err != nil {
+		return nil, err
+	}
+
+	rank := func(d *DockingStats) float64 {
+		switch order {
+		case ByDenialRate:
+			return d.DenialRate()
+		case ByHostile:
+			return float64(d.Reasons["Hostile"])
+		}
+		return float64(d.Requests())
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, rj := rank(&out[i]), rank(&out[j])
+		if ri != rj {
+			return ri > rj
+		}
+		return out[i].Requests() > out[j].Requests()
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// dockingBreakdown fills in the denial reasons and pad counts of the
+// stations in out, or only of marketID when it is not zero.
+func (s *Store) dockingBreakdown(since time.Time, marketID int64, index map[int64]int, out []DockingStats) error {
+	query := `SELECT market_id, granted, reason, landing_pad, COUNT(*) FROM docking_events WHERE timestamp >= ?`
+	args := []interface{}{since.UTC().Format(time.RFC3339)}
+	if marketID != 0 {
+		query += ` AND market_id = ?`
+		args = append(args, marketID)
+	}
+	query += ` GROUP BY market_id, granted, reason, landing_pad`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id         int64
+			granted    bool
+			reason     string
+			pad, count int
+		)
+		if err := rows.Scan(&id, &granted, &reason, &pad, &count); err != nil {
+			return err
+		}
+		i, ok := index[id]
+		if !ok {
+			continue
+		}
+		if granted {
+			if pad > 0 {
+				out[i].Pads[pad] += count
+			}
+			continue
+		}
+		if reason == "" {
+			reason = "Unknown"
+		}
+		out[i].Reasons[reason] += count
+	}
+	return rows.Err()
+}
+
+// DockingHistory returns the statistics of one station since the given
+// time, with its traffic counted per interval. It returns sql.ErrNoRows if
+// no request has been seen there since.
+func (s *Store) DockingHistory(marketID int64, since time.Time, interval time.Duration) (*DockingStats, error) {
+	d := &DockingStats{MarketID: marketID, Reasons: make(map[string]int), Pads: make(map[int]int)}
+	err := s.db.QueryRow(`SELECT MAX(station_name), MAX(station_type), SUM(granted), SUM(1 - granted)
+		FROM docking_events WHERE market_id = ? AND timestamp >= ? GROUP BY market_id`,
+		marketID, since.UTC().Format(time.RFC3339)).
+		Scan(&d.StationName, &d.StationType, &d.Granted, &d.Denied)
+	if err != nil {
+		return nil, err
+	}
+	stats := []DockingStats{*d}
+	if err := s.dockingBreakdown(since, marketID, map[int64]int{marketID: 0}, stats); err != nil {
+		return nil, err
+	}
+	*d = stats[0]
+
+	rows, err := s.db.Query(`SELECT granted, timestamp FROM docking_events
+		WHERE market_id = ? AND timestamp >= ? ORDER BY timestamp`, marketID, since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			granted bool
+			ts      string
+		)
+		if err := rows.Scan(&granted, &ts); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			continue
+		}
+		start := t.Truncate(interval)
+		if n := len(d.Traffic); n == 0 || !d.Traffic[n-1].Start.Equal(start) {
+			d.Traffic = append(d.Traffic, TrafficBucket{Start: start})
+		}
+		if granted {
+			d.Traffic[len(d.Traffic)-1].Granted++
+		} else {
+			d.Traffic[len(d.Traffic)-1].Denied++
+		}
+	}
+	return d, rows.Err()
+}
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"EDDN/eddn"
+	"EDDN/galaxy"
+)
+
+// Stock kinds as stored in station_stock.kind.
+const (
+	Modules = "module"
+	Ships   = "ship"
+)
+
+// SaveOutfitting records the modules a station sells, replacing its
+// previous list unless that one is newer.
+func (s *Store) SaveOutfitting(msg *eddn.OutfittingMessage) error {
+	return s.saveStock(Modules, int64(msg.MarketID), msg.StationName, msg.SystemName, msg.Timestamp, msg.Modules)
+}
+
+// SaveShipyard records the ships a station sells, replacing its previous
+// list unless that one is newer.
+func (s *Store) SaveShipyard(msg *eddn.ShipyardMessage) error {
+	return s.saveStock(Ships, int64(msg.MarketID), msg.StationName, msg.SystemName, msg.Timestamp, msg.Ships)
+}
+
+func (s *Store) saveStock(kind string, marketID int64, station, system string, ts eddn.Time, symbols []string) error {
+	if marketID == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO stock_stations (market_id, kind, station_name, system_name, timestamp)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (market_id, kind) DO UPDATE SET
+			station_name = excluded.station_name,
+			system_name = excluded.system_name,
+			timestamp = excluded.timestamp
+		WHERE excluded.timestamp >= stock_stations.timestamp`,
+		marketID, kind, station, system, ts)
+	if err != nil {
+		return err
+	}
+	// An older list arriving late is ignored.
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM station_stock WHERE market_id = ? AND kind = ?`, marketID, kind); err != nil {
+		return err
+	}
+	insert, err := tx.Prepare(`INSERT INTO station_stock (market_id, kind, symbol) VALUES (?, ?, ?)
+		ON CONFLICT (market_id, kind, symbol) DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, sym := range symbols {
+		if _, err := insert.Exec(marketID, kind, strings.ToLower(sym)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Stockist is a station that last listed a module or ship for sale.
+type Stockist struct {
+	MarketID    int64       `json:"marketId"`
+	StationName string      `json:"stationName"`
+	SystemName  string      `json:"systemName"`
+	Timestamp   string      `json:"timestamp"`
+	Pos         *[3]float64 `json:"starPos,omitempty"`
+	Distance    *float64    `json:"distance,omitempty"`
+}
+
+// Stockists lists the stations whose latest list of the given kind has
+// symbol, most recently seen first.
+func (s *Store) Stockists(kind, symbol string, limit int) ([]Stockist, error) {
+	rows, err := s.db.Query(`SELECT t.market_id, t.station_name, t.system_name, t.timestamp
+		FROM station_stock k JOIN stock_stations t ON t.market_id = k.market_id AND t.kind = k.kind
+		WHERE k.kind = ? AND k.symbol = ?
+		ORDER BY t.timestamp DESC LIMIT ?`, kind, strings.ToLower(symbol), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Stockist
+	for rows.Next() {
+		var st Stockist
+		if err := rows.Scan(&st.MarketID, &st.StationName, &st.SystemName, &st.Timestamp); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// NearestStockists lists the stations stocking symbol whose system position
+// is known, nearest to centre first.
+func (s *Store) NearestStockists(kind, symbol string, centre [3]float64, limit int) ([]Stockist, error) {
+	rows, err := s.db.Query(`SELECT t.market_id, t.station_name, t.system_name, t.timestamp, y.x, y.y, y.z
+		FROM station_stock k JOIN stock_stations t ON t.market_id = k.market_id AND t.kind = k.kind
+		JOIN systems y ON y.name = t.system_name COLLATE NOCASE
+		WHERE k.kind = ? AND k.symbol = ?`, kind, strings.ToLower(symbol))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Stockist
+	seen := make(map[int64]bool)
+	for rows.Next() {
+		var (
+			st  Stockist
+			pos [3]float64
+		)
+		if err := rows.Scan(&st.MarketID, &st.StationName, &st.SystemName, &st.Timestamp,
+			&pos[0], &pos[1], &pos[2]); err != nil {
+			return nil, err
+		}
+		// A system name seen under two addresses would join twice.
+		if seen[st.MarketID] {
+			continue
+		}
+		seen[st.MarketID] = true
+		d := galaxy.Distance(centre, pos)
+		st.Pos, st.Distance = &pos, &d
+		out = append(out, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return *out[i].Distance < *out[j].Distance })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// StockedSymbols lists every symbol of the given kind some station
+// currently sells.
+func (s *Store) StockedSymbols(kind string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT symbol FROM station_stock WHERE kind = ? ORDER BY symbol`, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var sym string
+		if err := rows.Scan(&sym); err != nil {
+			return nil, err
+		}
+		out = append(out, sym)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"EDDN/eddn"
+	"EDDN/stations"
+)
+
+// Market is the latest known snapshot of a station's market.
+type Market struct {
+	MarketID             int64          `json:"marketId"`
+	StationName          string         `json:"stationName"`
+	SystemName           string         `json:"systemName"`
+	StationType          string         `json:"stationType,omitempty"`
+	CarrierDockingAccess string         `json:"carrierDockingAccess,omitempty"`
+	Economies            []eddn.Economy `json:"economies"`
+	Prohibited           []string       `json:"prohibited"`
+	Horizons             bool           `json:"horizons"`
+	Odyssey              bool           `json:"odyssey"`
+	Timestamp            string         `json:"timestamp"`
+	GatewayTimestamp     string         `json:"gatewayTimestamp"`
+}
+
+// Price is one commodity line of a market snapshot.
+type Price struct {
+	MarketID      int64  `json:"marketId"`
+	Name          string `json:"name"`
+	MeanPrice     int64  `json:"meanPrice"`
+	BuyPrice      int64  `json:"buyPrice"`
+	Stock         int64  `json:"stock"`
+	StockBracket  int64  `json:"stockBracket"`
+	SellPrice     int64  `json:"sellPrice"`
+	Demand        int64  `json:"demand"`
+	DemandBracket int64  `json:"demandBracket"`
+	Timestamp     string `json:"timestamp"`
+}
+
+// Listing pairs a commodity price with the market it was seen at.
+type Listing struct {
+	Market Market `json:"market"`
+	Price  Price  `json:"price"`
+}
+
+// Carriers selects whether fleet carriers are included in a query.
+type Carriers int
+
+const (
+	AnyMarket Carriers = iota
+	OnlyCarriers
+	NoCarriers
+)
+
+// Filter narrows market queries. The zero value matches everything.
+type Filter struct {
+	// MaxAge drops markets whose snapshot is older than this.
+	MaxAge time.Duration
+	// Pad is the size of landing pad the market must offer: "S", "M" or
+	// "L". A larger pad also serves, and any other value is an error.
+	Pad string
+	// StationType, when set, must match exactly (e.g. "Coriolis").
+	StationType string
+	Carriers    Carriers
+}
+
+const carrierType = "FleetCarrier"
+
+// padSizes lists, for each Pad value, the landing pads big enough for it.
+var padSizes = map[string][]string{
+	"S": {"Small", "Medium", "Large"},
+	"M": {"Medium", "Large"},
+	"L": {"Large"},
+}
+
+func (f Filter) where(b *strings.Builder, args []interface{}) ([]interface{}, error) {
+	if f.MaxAge > 0 {
+		b.WriteString(" AND m.timestamp >= ?")
+		args = append(args, time.Now().Add(-f.MaxAge).UTC().Format(time.RFC3339))
+	}
+	if f.Pad != "" {
+		pad := strings.ToUpper(f.Pad)
+		sizes, ok := padSizes[pad]
+		if !ok {
+			return nil, fmt.Errorf("invalid pad %q, want S, M or L", f.Pad)
+		}
+		// Pad counts come from the station directory when a docking has
+		// reported them. Otherwise only large pads can be ruled out, by
+		// station type.
+		counts := make([]string, len(sizes))
+		for i, size := range sizes {
+			counts[i] = "IFNULL(json_extract(s.data, '$.landingPads." + size + "'), 0)"
+		}
+		fallback := "1"
+		if pad == "L" {
+			fallback = "m.station_type NOT IN (?" + strings.Repeat(", ?", len(stations.NoLargePadTypes)-1) + ")"
+		}
+		b.WriteString(` AND COALESCE((SELECT CASE WHEN json_extract(s.data, '$.landingPads') IS NULL THEN NULL
+			ELSE ` + strings.Join(counts, " + ") + ` > 0 END
+			FROM stations s WHERE s.market_id = m.market_id), ` + fallback + `)`)
+		if pad == "L" {
+			for _, t := range stations.NoLargePadTypes {
+				args = append(args, t)
+			}
+		}
+	}
+	if f.StationType != "" {
+		b.WriteString(" AND m.station_type = ?")
+		args = append(args, f.StationType)
+	}
+	switch f.Carriers {
+	case OnlyCarriers:
+		b.WriteString(" AND (m.station_type = ? OR m.carrier_docking_access != '')")
+		args = append(args, carrierType)
+	case NoCarriers:
+		b.WriteString(" AND m.station_type != ? AND m.carrier_docking_access = ''")
+		args = append(args, carrierType)
+	}
+	return args, nil
+}
+
+const marketColumns = `m.market_id, m.station_name, m.system_name, m.station_type, m.carrier_docking_access,
+	m.economies, m.prohibited, m.horizons, m.odyssey, m.timestamp, m.gateway_timestamp`
+
+const priceColumns = `c.market_id, c.name, c.mean_price, c.buy_price, c.stock, c.stock_bracket,
+	c.sell_price, c.demand, c.demand_bracket, c.timestamp`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMarket(row scanner, extra ...interface{}) (Market, error) {
+	var m Market
+	var economies, prohibited string
+	dest := append([]interface{}{&m.MarketID, &m.StationName, &m.SystemName, &m.StationType, &m.CarrierDockingAccess,
+		&economies, &prohibited, &m.Horizons, &m.Odyssey, &m.Timestamp, &m.GatewayTimestamp}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal([]byte(economies), &m.Economies); err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal([]byte(prohibited), &m.Prohibited); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func (p *Price) fields() []interface{} {
+	return []interface{}{&p.MarketID, &p.Name, &p.MeanPrice, &p.BuyPrice, &p.Stock, &p.StockBracket,
+		&p.SellPrice, &p.Demand, &p.DemandBracket, &p.Timestamp}
+}
+
+// BestBuy lists the markets selling commodity name, cheapest first.
+func (s *Store) BestBuy(name string, f Filter, limit int) ([]Listing, error) {
+	return s.listings(name, f, "c.buy_price > 0 AND c.stock > 0", "c.buy_price ASC", limit)
+}
+
+// BestSell lists the markets buying commodity name, best paying first.
+func (s *Store) BestSell(name string, f Filter, limit int) ([]Listing, error) {
+	return s.listings(name, f, "c.sell_price > 0 AND c.demand > 0", "c.sell_price DESC", limit)
+}
+
+// SellersIn lists the markets selling commodity name in any of the given
+// systems, cheapest first.
+func (s *Store) SellersIn(name string, systems []string, f Filter) ([]Listing, error) {
+	if len(systems) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(systems))
+	for _, sys := range systems {
+		args = append(args, sys)
+	}
+	cond := "c.buy_price > 0 AND c.stock > 0 AND m.system_name COLLATE NOCASE IN (?" + strings.Repeat(", ?", len(systems)-1) + ")"
+	return s.listings(name, f, cond, "c.buy_price ASC", -1, args...)
+}
+
+func (s *Store) listings(name string, f Filter, cond, order string, limit int, condArgs ...interface{}) ([]Listing, error) {
+	var b strings.Builder
+	b.WriteString(`SELECT ` + marketColumns + `, ` + priceColumns + `
+		FROM market_commodities c JOIN markets m ON m.market_id = c.market_id
+		WHERE c.name = ? COLLATE NOCASE AND ` + cond)
+	args, err := f.where(&b, append([]interface{}{name}, condArgs...))
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString(" ORDER BY " + order + " LIMIT ?")
+	args = append(args, limit)
+
+	rows, err := s.db.Query(b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Listing
+	for rows.Next() {
+		var l Listing
+		if l.Market, err = scanMarket(rows, l.Price.fields()...); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// Market returns the latest snapshot of a market and its current prices.
+// It returns sql.ErrNoRows if the market has never been seen.
+func (s *Store) Market(marketID int64) (*Market, []Price, error) {
+	m, err := scanMarket(s.db.QueryRow(`SELECT `+marketColumns+` FROM markets m WHERE m.market_id = ?`, marketID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT `+priceColumns+` FROM market_commodities c WHERE c.market_id = ? ORDER BY c.name`, marketID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var prices []Price
+	for rows.Next() {
+		var p Price
+		if err := rows.Scan(p.fields()...); err != nil {
+			return nil, nil, err
+		}
+		prices = append(prices, p)
+	}
+	return &m, prices, rows.Err()
+}
+
+// SystemMarkets lists the markets last seen in the named system.
+func (s *Store) SystemMarkets(system string, f Filter) ([]Market, error) {
+	var b strings.Builder
+	b.WriteString(`SELECT ` + marketColumns + ` FROM markets m WHERE m.system_name = ? COLLATE NOCASE`)
+	args, err := f.where(&b, []interface{}{system})
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString(" ORDER BY m.station_name")
+
+	rows, err := s.db.Query(b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Market
+	for rows.Next() {
+		m, err := scanMarket(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// IsNotFound reports whether err means the requested row does not exist.
+func IsNotFound(err error) bool {
+	return err == sql.ErrNoRows
+}
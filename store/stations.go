@@ -0,0 +1,84 @@
+package store
+
+import (
+	"encoding/json"
+
+	"EDDN/stations"
+)
+
+// SaveStation stores the merged record of a station, replacing the previous
+// one. The record is kept as JSON, with the name and system in columns for
+// lookups.
+func (s *Store) SaveStation(st stations.Station) error {
+	if st.MarketID == 0 {
+		return nil
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO stations (market_id, name, system_name, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (market_id) DO UPDATE SET
+			name = excluded.name, system_name = excluded.system_name, data = excluded.data`,
+		st.MarketID, st.Name, st.SystemName, string(data))
+	return err
+}
+
+// LoadStations adds every stored station to d.
+func (s *Store) LoadStations(d *stations.Directory) error {
+	rows, err := s.db.Query(`SELECT data FROM stations`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		st, err := scanStation(rows)
+		if err != nil {
+			return err
+		}
+		d.Add(st)
+	}
+	return rows.Err()
+}
+
+// StationsInSystem lists the stations last seen in the named system that
+// match q, by name.
+func (s *Store) StationsInSystem(system string, q stations.Query) ([]stations.Station, error) {
+	rows, err := s.db.Query(`SELECT data FROM stations WHERE system_name = ? COLLATE NOCASE ORDER BY name`, system)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []stations.Station
+	for rows.Next() {
+		st, err := scanStation(rows)
+		if err != nil {
+			return nil, err
+		}
+		if q.Matches(&st) {
+			out = append(out, st)
+		}
+	}
+	return out, rows.Err()
+}
+
+// Station returns the merged record of a station. It returns
+// sql.ErrNoRows if the station has never been seen.
+func (s *Store) Station(marketID int64) (stations.Station, error) {
+	return scanStation(s.db.QueryRow(`SELECT data FROM stations WHERE market_id = ?`, marketID))
+}
+
+func scanStation(row scanner) (stations.Station, error) {
+	var (
+		st   stations.Station
+		data string
+	)
+	if err := row.Scan(&data); err != nil {
+		return st, err
+	}
+	err := json.Unmarshal([]byte(data), &st)
+	return st, err
+}
@@ -0,0 +1,163 @@
+// Package store persists decoded EDDN data to a local SQLite database.
+package store
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS markets (
+	market_id              INTEGER PRIMARY KEY,
+	station_name           TEXT NOT NULL,
+	system_name            TEXT NOT NULL,
+	station_type           TEXT NOT NULL DEFAULT '',
+	carrier_docking_access TEXT NOT NULL DEFAULT '',
+	economies              TEXT NOT NULL DEFAULT '[]',
+	prohibited             TEXT NOT NULL DEFAULT '[]',
+	horizons               INTEGER NOT NULL DEFAULT 0,
+	odyssey                INTEGER NOT NULL DEFAULT 0,
+	timestamp              TEXT NOT NULL,
+	gateway_timestamp      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS markets_system ON markets (system_name COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS market_commodities (
+	market_id      INTEGER NOT NULL,
+	name           TEXT NOT NULL,
+	mean_price     INTEGER NOT NULL,
+	buy_price      INTEGER NOT NULL,
+	stock          INTEGER NOT NULL,
+	stock_bracket  INTEGER NOT NULL,
+	sell_price     INTEGER NOT NULL,
+	demand         INTEGER NOT NULL,
+	demand_bracket INTEGER NOT NULL,
+	timestamp      TEXT NOT NULL,
+	PRIMARY KEY (market_id, name)
+);
+CREATE INDEX IF NOT EXISTS market_commodities_name ON market_commodities (name COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS commodity_history (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	market_id      INTEGER NOT NULL,
+	name           TEXT NOT NULL,
+	mean_price     INTEGER NOT NULL,
+	buy_price      INTEGER NOT NULL,
+	stock          INTEGER NOT NULL,
+	stock_bracket  INTEGER NOT NULL,
+	sell_price     INTEGER NOT NULL,
+	demand         INTEGER NOT NULL,
+	demand_bracket INTEGER NOT NULL,
+	timestamp      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS commodity_history_market ON commodity_history (market_id, timestamp);
+CREATE INDEX IF NOT EXISTS commodity_history_name ON commodity_history (name COLLATE NOCASE, timestamp);
+
+CREATE TABLE IF NOT EXISTS systems (
+	system_address INTEGER PRIMARY KEY,
+	name           TEXT NOT NULL,
+	x              REAL NOT NULL,
+	y              REAL NOT NULL,
+	z              REAL NOT NULL,
+	timestamp      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS systems_name ON systems (name COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS systems_x ON systems (x);
+
+CREATE TABLE IF NOT EXISTS carriers (
+	carrier_id TEXT PRIMARY KEY,
+	name       TEXT NOT NULL DEFAULT '',
+	market_id  INTEGER NOT NULL DEFAULT 0,
+	timestamp  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS carrier_orders (
+	carrier_id TEXT NOT NULL,
+	side       TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	count      INTEGER NOT NULL,
+	price      INTEGER NOT NULL,
+	timestamp  TEXT NOT NULL,
+	PRIMARY KEY (carrier_id, side, name)
+);
+CREATE INDEX IF NOT EXISTS carrier_orders_name ON carrier_orders (name, side);
+
+CREATE TABLE IF NOT EXISTS carrier_order_history (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	carrier_id TEXT NOT NULL,
+	side       TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	count      INTEGER NOT NULL,
+	price      INTEGER NOT NULL,
+	timestamp  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS carrier_order_history_carrier ON carrier_order_history (carrier_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS stock_stations (
+	market_id    INTEGER NOT NULL,
+	kind         TEXT NOT NULL,
+	station_name TEXT NOT NULL,
+	system_name  TEXT NOT NULL,
+	timestamp    TEXT NOT NULL,
+	PRIMARY KEY (market_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS station_stock (
+	market_id INTEGER NOT NULL,
+	kind      TEXT NOT NULL,
+	symbol    TEXT NOT NULL,
+	PRIMARY KEY (market_id, kind, symbol)
+);
+CREATE INDEX IF NOT EXISTS station_stock_symbol ON station_stock (kind, symbol);
+
+CREATE TABLE IF NOT EXISTS stations (
+	market_id   INTEGER PRIMARY KEY,
+	name        TEXT NOT NULL DEFAULT '',
+	system_name TEXT NOT NULL DEFAULT '',
+	data        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS stations_system ON stations (system_name COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS docking_events (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	market_id    INTEGER NOT NULL,
+	station_name TEXT NOT NULL,
+	station_type TEXT NOT NULL DEFAULT '',
+	granted      INTEGER NOT NULL,
+	landing_pad  INTEGER NOT NULL DEFAULT 0,
+	reason       TEXT NOT NULL DEFAULT '',
+	timestamp    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS docking_events_time ON docking_events (timestamp);
+CREATE INDEX IF NOT EXISTS docking_events_market ON docking_events (market_id, timestamp);
+`
+
+// Store wraps the SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens or creates the database at path and makes sure the tables exist.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only allows one writer; a single connection avoids lock errors.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB exposes the underlying database for ad-hoc queries.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
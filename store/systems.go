@@ -0,0 +1,141 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"EDDN/galaxy"
+)
+
+// SaveSystem records where a star system is. Later sightings overwrite
+// earlier ones so renamed or corrected systems converge.
+func (s *Store) SaveSystem(sys galaxy.System, seen time.Time) error {
+	if sys.Address == 0 || sys.Name == "" {
+		return nil
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO systems (system_address, name, x, y, z, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (system_address) DO UPDATE SET
+			name = excluded.name, x = excluded.x, y = excluded.y, z = excluded.z,
+			timestamp = excluded.timestamp
+		WHERE excluded.timestamp >= systems.timestamp`,
+		sys.Address, sys.Name, sys.Pos[0], sys.Pos[1], sys.Pos[2], seen.UTC().Format(time.RFC3339))
+	return err
+}
+
+// SystemByName looks a system up case-insensitively. It returns
+// sql.ErrNoRows if its position has never been seen.
+func (s *Store) SystemByName(name string) (galaxy.System, error) {
+	var sys galaxy.System
+	err := s.db.QueryRow(`SELECT system_address, name, x, y, z FROM systems WHERE name = ? COLLATE NOCASE
+		ORDER BY timestamp DESC LIMIT 1`, name).
+		Scan(&sys.Address, &sys.Name, &sys.Pos[0], &sys.Pos[1], &sys.Pos[2])
+	return sys, err
+}
+
+// LoadSystems adds every stored system to c.
+func (s *Store) LoadSystems(c *galaxy.Catalogue) error {
+	rows, err := s.db.Query(`SELECT system_address, name, x, y, z FROM systems`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sys galaxy.System
+		if err := rows.Scan(&sys.Address, &sys.Name, &sys.Pos[0], &sys.Pos[1], &sys.Pos[2]); err != nil {
+			return err
+		}
+		c.Add(sys)
+	}
+	return rows.Err()
+}
+
+// PlacedMarket is a market whose system position is known.
+type PlacedMarket struct {
+	Market
+	Pos    [3]float64 `json:"starPos"`
+	Prices []Price    `json:"commodities"`
+}
+
+// MarketsWithin loads every market matching f whose system lies within
+// radius light years of centre, together with its current prices.
+func (s *Store) MarketsWithin(centre [3]float64, radius float64, f Filter) ([]PlacedMarket, error) {
+	var b strings.Builder
+	b.WriteString(`SELECT ` + marketColumns + `, y.x, y.y, y.z
+		FROM markets m JOIN systems y ON y.name = m.system_name COLLATE NOCASE
+		WHERE y.x BETWEEN ? AND ? AND y.y BETWEEN ? AND ? AND y.z BETWEEN ? AND ?`)
+	args := []interface{}{centre[0] - radius, centre[0] + radius, centre[1] - radius, centre[1] + radius,
+		centre[2] - radius, centre[2] + radius}
+	args, err := f.where(&b, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PlacedMarket
+	index := make(map[int64]int)
+	for rows.Next() {
+		var pm PlacedMarket
+		if pm.Market, err = scanMarket(rows, &pm.Pos[0], &pm.Pos[1], &pm.Pos[2]); err != nil {
+			return nil, err
+		}
+		if galaxy.Distance(centre, pm.Pos) > radius {
+			continue
+		}
+		// A system name seen under two addresses would join twice.
+		if _, dup := index[pm.MarketID]; dup {
+			continue
+		}
+		index[pm.MarketID] = len(out)
+		out = append(out, pm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	ids := make([]interface{}, 0, len(out))
+	for _, pm := range out {
+		ids = append(ids, pm.MarketID)
+	}
+	// Stay well under SQLite's limit on bound parameters.
+	const chunk = 500
+	for start := 0; start < len(ids); start += chunk {
+		end := start + chunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := s.loadPrices(ids[start:end], index, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) loadPrices(ids []interface{}, index map[int64]int, out []PlacedMarket) error {
+	rows, err := s.db.Query(`SELECT `+priceColumns+` FROM market_commodities c
+		WHERE c.market_id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Price
+		if err := rows.Scan(p.fields()...); err != nil {
+			return err
+		}
+		pm := &out[index[p.MarketID]]
+		pm.Prices = append(pm.Prices, p)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,149 @@
+// Package validate checks EDDN messages against the gateway's JSON Schemas.
+//
+// The schemas under schemas/ are copies of the EDDN project's own
+// (https://github.com/EDCD/EDDN/tree/live/schemas) and should be refreshed
+// from there when the gateway changes them. Files are named
+// <schema>-v<version>.0.json, matching upstream.
+package validate
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"EDDN/eddn"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed schemas/*.json
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(.+)-v(\d+)\.0\.json$`)
+
+// Validator holds the compiled schemas keyed by $schemaRef. It is safe for
+// concurrent use.
+type Validator struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+// New compiles every bundled schema.
+func New() (*Validator, error) {
+	entries, err := files.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft4
+	refs := make(map[string]string)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		data, err := files.ReadFile(path.Join("schemas", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		version, _ := strconv.Atoi(m[2])
+		ref := eddn.Schema{Family: m[1], Version: version}.Ref()
+		if err := c.AddResource(ref, bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("loading schema %s: %w", e.Name(), err)
+		}
+		refs[ref] = e.Name()
+	}
+
+	v := &Validator{schemas: make(map[string]*jsonschema.Schema)}
+	for ref, name := range refs {
+		s, err := c.Compile(ref)
+		if err != nil {
+			return nil, fmt.Errorf("compiling schema %s: %w", name, err)
+		}
+		v.schemas[ref] = s
+	}
+	return v, nil
+}
+
+// Schemas returns the $schemaRefs a schema is bundled for.
+func (v *Validator) Schemas() []string {
+	refs := make([]string, 0, len(v.schemas))
+	for ref := range v.schemas {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Violation is a single reason a message failed its schema.
+type Violation struct {
+	// Path is the JSON pointer of the offending value, e.g. /message/StarPos.
+	Path    string
+	Message string
+}
+
+// Error lists every violation found in one message.
+type Error struct {
+	SchemaRef  string
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Path + ": " + v.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validate checks the whole document env came in against the schema for
+// its $schemaRef; /test messages use the live schema. Messages with no
+// bundled schema pass. Failures are
+// returned as *Error.
+func (v *Validator) Validate(env *eddn.Envelope) error {
+	s, ok := v.schemas[env.Schema.Live().Ref()]
+	if !ok || env.Data == nil {
+		return nil
+	}
+	// Numbers must stay json.Number so integers are told apart from floats.
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(env.Data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	err := s.Validate(doc)
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+	e := &Error{SchemaRef: env.SchemaRef}
+	collect(ve, e)
+	return e
+}
+
+// collect flattens the cause tree into its leaves, which carry the useful
+// messages; the inner nodes only say which keyword failed.
+func collect(ve *jsonschema.ValidationError, e *Error) {
+	if len(ve.Causes) == 0 {
+		p := ve.InstanceLocation
+		if p == "" {
+			p = "/"
+		}
+		msg := ve.Message
+		if strings.HasSuffix(ve.AbsoluteKeywordLocation, "/disallowed/not") {
+			// Upstream marks forbidden properties with a schema nothing matches.
+			msg = "property is not allowed"
+		}
+		e.Violations = append(e.Violations, Violation{Path: p, Message: msg})
+		return
+	}
+	for _, c := range ve.Causes {
+		collect(c, e)
+	}
+}